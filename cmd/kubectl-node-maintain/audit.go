@@ -0,0 +1,51 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/futuretea/kubectl-node-maintain/pkg/plugin"
+)
+
+func newAuditCommand(o *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect the audit log of maintenance actions",
+	}
+	cmd.AddCommand(newAuditShowCommand(o))
+	return cmd
+}
+
+func newAuditShowCommand(o *rootOptions) *cobra.Command {
+	var (
+		filter plugin.AuditFilter
+		since  time.Duration
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the entries of the audit log",
+		Long:  `Show the entries of the audit log given by --audit-log, oldest first, optionally filtered.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+			entries, err := plugin.ReadAuditLog(o.auditLog, filter)
+			if err != nil {
+				return err
+			}
+			return plugin.PrintAuditEntries(cmd.OutOrStdout(), entries, output)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&filter.Node, "node", "", "Only show entries for this node")
+	flags.StringVar(&filter.Action, "action", "", "Only show entries for this action, e.g. cordon or drain")
+	flags.StringVar(&filter.User, "user", "", "Only show entries made by this user")
+	flags.DurationVar(&since, "since", 0, "Only show entries newer than this, e.g. 24h")
+	flags.StringVarP(&output, "output", "o", "", "Output format, empty for a table or json")
+	return cmd
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newCleanupCommand(o *rootOptions) *cobra.Command {
+	var selector string
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "cleanup [NODE...]",
+		Short: "Delete Failed, Completed and Evicted pods",
+		Long: `Non-interactive cleanup of the finished pods (phase Failed or Succeeded, or reason Evicted) on
+the given nodes, on all nodes matching --selector, or on every node with --all. The nodes are not cordoned.
+Exits 0 when every node succeeded, 1 when the command could not run and 2 when any node failed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := o.newPlugin()
+			if err != nil {
+				return err
+			}
+
+			results, err := p.Cleanup(cmd.Context(), args, selector, all)
+			if err != nil {
+				return err
+			}
+			return o.printResults(cmd, results)
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to pick nodes, e.g. node-role.kubernetes.io/worker=")
+	cmd.Flags().BoolVar(&all, "all", false, "Clean up every node in the cluster")
+	return cmd
+}
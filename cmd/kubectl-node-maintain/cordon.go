@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/futuretea/kubectl-node-maintain/pkg/plugin"
+)
+
+func newCordonCommand(o *rootOptions) *cobra.Command {
+	return newCordonOrUncordonCommand(o, true)
+}
+
+func newUncordonCommand(o *rootOptions) *cobra.Command {
+	return newCordonOrUncordonCommand(o, false)
+}
+
+func newCordonOrUncordonCommand(o *rootOptions, desired bool) *cobra.Command {
+	var selector string
+
+	action := plugin.MsgCordon
+	short := "Mark nodes as unschedulable"
+	if !desired {
+		action = plugin.MsgUncordon
+		short = "Mark nodes as schedulable"
+	}
+
+	cmd := &cobra.Command{
+		Use:   action + " [NODE...]",
+		Short: short,
+		Long: `Non-interactive ` + action + ` of the given nodes, or of all nodes matching --selector.
+Exits 0 when every node succeeded, 1 when the command could not run and 2 when any node failed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := o.newPlugin()
+			if err != nil {
+				return err
+			}
+
+			results, err := p.Cordon(cmd.Context(), args, selector, desired)
+			if err != nil {
+				return err
+			}
+			return o.printResults(cmd, results)
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to pick nodes, e.g. node-role.kubernetes.io/worker=")
+	return cmd
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/futuretea/kubectl-node-maintain/pkg/plugin"
+)
+
+func newDrainCommand(o *rootOptions) *cobra.Command {
+	var selector string
+	settings := plugin.DefaultDrainSettings()
+
+	cmd := &cobra.Command{
+		Use:   "drain [NODE...]",
+		Short: "Cordon and drain nodes",
+		Long: `Non-interactive cordon and drain of the given nodes, or of all nodes matching --selector.
+Exits 0 when every node succeeded, 1 when the command could not run and 2 when any node failed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := o.newPlugin()
+			if err != nil {
+				return err
+			}
+
+			results, err := p.Drain(cmd.Context(), args, selector, settings)
+			if err != nil {
+				return err
+			}
+			return o.printResults(cmd, results)
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to pick nodes, e.g. node-role.kubernetes.io/worker=")
+	addDrainFlags(cmd, &settings)
+	return cmd
+}
+
+// addDrainFlags registers one flag per drain option on cmd
+func addDrainFlags(cmd *cobra.Command, settings *plugin.DrainSettings) {
+	flags := cmd.Flags()
+	flags.BoolVar(&settings.Force, "force", settings.Force,
+		"Continue even if there are pods not managed by a controller")
+	flags.IntVar(&settings.GracePeriodSeconds, "grace-period", settings.GracePeriodSeconds,
+		"Seconds given to each pod to terminate gracefully. If negative, the pod's own value is used")
+	flags.DurationVar(&settings.Timeout, "timeout", settings.Timeout,
+		"The length of time to wait before giving up, zero means infinite")
+	flags.BoolVar(&settings.DeleteEmptyDirData, "delete-emptydir-data", settings.DeleteEmptyDirData,
+		"Continue even if there are pods using emptyDir (local data that will be deleted when the node is drained)")
+	flags.BoolVar(&settings.IgnoreAllDaemonSets, "ignore-daemonsets", settings.IgnoreAllDaemonSets,
+		"Ignore DaemonSet-managed pods")
+	flags.StringVar(&settings.PodSelector, "pod-selector", settings.PodSelector,
+		"Label selector to filter pods on the node")
+	flags.IntVar(&settings.SkipWaitForDeleteTimeoutSeconds, "skip-wait-for-delete-timeout", settings.SkipWaitForDeleteTimeoutSeconds,
+		"If pod DeletionTimestamp is older than N seconds, skip waiting for the pod")
+	flags.BoolVar(&settings.DisableEviction, "disable-eviction", settings.DisableEviction,
+		"Force drain to use delete, even if eviction is supported. This will bypass checking PodDisruptionBudgets")
+}
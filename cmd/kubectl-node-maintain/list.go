@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/futuretea/kubectl-node-maintain/pkg/plugin"
+)
+
+const fieldSelectorHelp = "Node query to pick nodes, e.g. 'role=worker status=NotReady age>30d version<1.29 label:zone=us-east-1a cordoned'"
+
+const outputHelp = "Output format. One of: wide, json, yaml, jsonpath=<template>, custom-columns=<spec>"
+
+func newListCommand(o *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Print nodes or pods in a machine-readable format",
+	}
+	cmd.AddCommand(
+		newListNodesCommand(o),
+		newListPodsCommand(o),
+	)
+	return cmd
+}
+
+func newListNodesCommand(o *rootOptions) *cobra.Command {
+	var selector, fieldSelector, output string
+	sortBy := plugin.SortByName
+
+	cmd := &cobra.Command{
+		Use:   "nodes",
+		Short: "List nodes with readiness, schedulability, roles, version and conditions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, err := plugin.NewNodeFilter(selector, fieldSelector)
+			if err != nil {
+				return err
+			}
+			p, err := o.newPlugin()
+			if err != nil {
+				return err
+			}
+
+			// The plain table has no PODS column
+			nodes, err := p.ListNodes(cmd.Context(), filter, sortBy, output != "")
+			if err != nil {
+				return fmt.Errorf("failed to list nodes: %v", err)
+			}
+			return plugin.PrintNodes(cmd.OutOrStdout(), nodes, output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to pick nodes, e.g. node-role.kubernetes.io/worker=")
+	cmd.Flags().StringVar(&fieldSelector, "field-selector", "", fieldSelectorHelp)
+	cmd.Flags().StringVarP(&output, "output", "o", "", outputHelp)
+	cmd.Flags().StringVar(&sortBy, "sort-by", sortBy, "Column the nodes are ordered by: name, status, roles, age, version, ip, pods, or cpu or memory usage as a share of allocatable, busiest first")
+	return cmd
+}
+
+func newListPodsCommand(o *rootOptions) *cobra.Command {
+	var node, output string
+
+	cmd := &cobra.Command{
+		Use:   "pods --node NODE",
+		Short: "List the pods on a node with owner and phase",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := o.newPlugin()
+			if err != nil {
+				return err
+			}
+
+			pods, err := p.ListPods(cmd.Context(), node)
+			if err != nil {
+				return fmt.Errorf("failed to list pods on node %s: %v", node, err)
+			}
+			return plugin.PrintPods(cmd.OutOrStdout(), pods, output)
+		},
+	}
+
+	cmd.Flags().StringVar(&node, "node", "", "Node whose pods are listed")
+	cmd.Flags().StringVarP(&output, "output", "o", "", outputHelp)
+	_ = cmd.MarkFlagRequired("node")
+	return cmd
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -10,36 +14,185 @@ import (
 	"github.com/futuretea/kubectl-node-maintain/pkg/plugin"
 )
 
+// Exit codes returned by the non-interactive subcommands
+const (
+	exitOK             = 0
+	exitError          = 1
+	exitPartialFailure = 2
+	exitInterrupted    = 130
+)
+
+// exitErr carries the process exit code for an error returned by a command
+type exitErr struct {
+	code int
+	err  error
+}
+
+func (e *exitErr) Error() string {
+	return e.err.Error()
+}
+
+// rootOptions holds the flags shared by the root command and its subcommands
+type rootOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	dryRun      string
+	auditLog    string
+	events      bool
+}
+
 func main() {
+	// The first SIGINT or SIGTERM cancels the running action and lets it
+	// report what it handled; a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	rootCmd := NewRootCommand()
-	if err := rootCmd.Execute(); err != nil {
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		var e *exitErr
+		switch {
+		case ctx.Err() != nil:
+			os.Exit(exitInterrupted)
+		case errors.As(err, &e):
+			os.Exit(e.code)
+		}
+		os.Exit(exitError)
 	}
+	os.Exit(exitOK)
 }
 
 func NewRootCommand() *cobra.Command {
-	configFlags := genericclioptions.NewConfigFlags(true)
+	o := &rootOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+	}
+	drainSettings := plugin.DefaultDrainSettings()
+	deleteSettings := plugin.DefaultDeleteSettings()
+	defaultTaint := plugin.DefaultMaintenanceTaint()
+	var maintenanceTaint, selector, fieldSelector string
 
 	cmd := &cobra.Command{
-		Use:   "node-maintain",
-		Short: "Node maintenance plugin",
-		Long:  `A kubectl plugin for managing Kubernetes node maintenance, supporting node pod management and cleanup operations`,
+		Use:           "node-maintain",
+		Short:         "Node maintenance plugin",
+		Long:          `A kubectl plugin for managing Kubernetes node maintenance, supporting node pod management and cleanup operations`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			config, err := configFlags.ToRESTConfig()
+			taint, err := plugin.ParseTaint(maintenanceTaint)
 			if err != nil {
-				return fmt.Errorf("failed to get kubeconfig: %v", err)
+				return err
 			}
-
-			p, err := plugin.NewPlugin(config)
+			if err := plugin.ValidateDeleteMethod(deleteSettings.Method); err != nil {
+				return err
+			}
+			filter, err := plugin.NewNodeFilter(selector, fieldSelector)
+			if err != nil {
+				return err
+			}
+			p, err := o.newPlugin(
+				plugin.WithDrainSettings(drainSettings),
+				plugin.WithDeleteSettings(deleteSettings),
+				plugin.WithMaintenanceTaint(taint),
+				plugin.WithNodeFilter(filter),
+			)
 			if err != nil {
-				return fmt.Errorf("failed to create plugin: %v", err)
+				return err
 			}
 
-			return p.Run()
+			return p.Run(cmd.Context())
 		},
 	}
 
-	configFlags.AddFlags(cmd.Flags())
+	o.configFlags.AddFlags(cmd.PersistentFlags())
+	cmd.PersistentFlags().StringVar(&o.dryRun, "dry-run", plugin.DryRunNone,
+		`Must be "none", "client" or "server". Report what every action would change without changing the cluster`)
+	cmd.PersistentFlags().StringVar(&o.auditLog, "audit-log", plugin.DefaultAuditLogPath(),
+		"Append every change made to the cluster to this JSON-lines file, empty to disable")
+	cmd.PersistentFlags().BoolVar(&o.events, "record-events", true,
+		"Record Kubernetes Events on the nodes and pods changed by an action")
+	cmd.Flags().StringVar(&maintenanceTaint, "maintenance-taint", defaultTaint.ToString(),
+		"Taint offered instead of, or along with, a cordon, as key[=value]:NoSchedule|NoExecute")
+	cmd.Flags().StringVar(&deleteSettings.Method, "delete-method", deleteSettings.Method,
+		"How the delete actions remove pods: force, delete (pod grace period), evict or evict-then-force")
+	cmd.Flags().DurationVar(&deleteSettings.Timeout, "delete-timeout", deleteSettings.Timeout,
+		"How long a blocked eviction is retried; evict-then-force force deletes pods not gone by then")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector limiting the nodes shown")
+	cmd.Flags().StringVar(&fieldSelector, "field-selector", "", fieldSelectorHelp)
+	addDrainFlags(cmd, &drainSettings)
+
+	cmd.AddCommand(
+		newCordonCommand(o),
+		newUncordonCommand(o),
+		newDrainCommand(o),
+		newCleanupCommand(o),
+		newRolloutCommand(o),
+		newPlanCommand(o),
+		newApplyCommand(o),
+		newResumeCommand(o),
+		newListCommand(o),
+		newAuditCommand(o),
+	)
 	return cmd
 }
+
+// newPlugin builds a plugin from the kubeconfig and dry-run flags
+func (o *rootOptions) newPlugin(opts ...plugin.Option) (*plugin.Plugin, error) {
+	dryRun, err := plugin.ParseDryRunStrategy(o.dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := o.configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig: %v", err)
+	}
+
+	opts = append(opts, plugin.WithDryRun(dryRun), plugin.WithEvents(o.events))
+	if o.auditLog != "" {
+		opts = append(opts, plugin.WithAuditLog(o.auditLog, o.kubeContext()))
+	}
+
+	p, err := plugin.NewPlugin(config, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plugin: %v", err)
+	}
+	return p, nil
+}
+
+// kubeContext returns the kubeconfig context in use, if it can be told
+func (o *rootOptions) kubeContext() string {
+	if o.configFlags.Context != nil && *o.configFlags.Context != "" {
+		return *o.configFlags.Context
+	}
+	raw, err := o.configFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	return raw.CurrentContext
+}
+
+// printResults writes the per-node summary, and on a dry run or after a
+// cancel every change, then turns failed node results into an exit error
+func (o *rootOptions) printResults(cmd *cobra.Command, results []plugin.NodeResult) error {
+	out := cmd.OutOrStdout()
+	if err := plugin.PrintNodeResults(out, results); err != nil {
+		return err
+	}
+	// A canceled action lists every pod it did and did not get to
+	if o.dryRun != plugin.DryRunNone || plugin.CountCanceled(results) > 0 {
+		fmt.Fprintln(out)
+		if err := plugin.PrintChanges(out, results); err != nil {
+			return err
+		}
+	}
+
+	if failed := plugin.CountFailed(results); failed > 0 {
+		return &exitErr{
+			code: exitPartialFailure,
+			err:  fmt.Errorf("%d of %d nodes failed", failed, len(results)),
+		}
+	}
+	return nil
+}
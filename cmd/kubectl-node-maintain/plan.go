@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/futuretea/kubectl-node-maintain/pkg/plugin"
+)
+
+func newPlanCommand(o *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "plan FILE",
+		Short: "Show what a maintenance plan would change",
+		Long: `Render a YAML maintenance plan against the live cluster without changing it.
+Every step runs as a client dry run, or as a server dry run with --dry-run=server.
+Use "-" to read the plan from stdin.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.dryRun == plugin.DryRunNone {
+				o.dryRun = plugin.DryRunClient
+			}
+			return o.applyPlan(cmd, args[0])
+		},
+	}
+}
+
+func newApplyCommand(o *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "apply FILE",
+		Short: "Run a maintenance plan",
+		Long: `Run the steps of a YAML maintenance plan in order. Use "-" to read the plan from stdin.
+Exits 0 when every node succeeded, 1 when the command could not run and 2 when any node failed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.applyPlan(cmd, args[0])
+		},
+	}
+}
+
+// applyPlan loads the plan file and runs it with the dry-run flag
+func (o *rootOptions) applyPlan(cmd *cobra.Command, path string) error {
+	plan, err := plugin.LoadPlan(path)
+	if err != nil {
+		return err
+	}
+	p, err := o.newPlugin()
+	if err != nil {
+		return err
+	}
+
+	results, err := p.ApplyPlan(cmd.Context(), plan, cmd.ErrOrStderr())
+	if err != nil {
+		if len(results) > 0 {
+			_ = o.printResults(cmd, results)
+		}
+		return err
+	}
+	return o.printResults(cmd, results)
+}
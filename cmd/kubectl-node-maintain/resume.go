@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/futuretea/kubectl-node-maintain/pkg/plugin"
+)
+
+func newResumeCommand(o *rootOptions) *cobra.Command {
+	var list, discard bool
+
+	cmd := &cobra.Command{
+		Use:   "resume [NODE...]",
+		Short: "Resume drains and deletions that did not finish",
+		Long: `Pick up the drains and pod deletions that stopped before they finished, e.g. after a
+dropped session. Their progress is kept in an annotation on each node, so any machine can resume.
+Without node names every unfinished operation is resumed.
+Exits 0 when every node succeeded, 1 when the command could not run and 2 when any node failed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list && discard {
+				return fmt.Errorf("cannot specify both --list and --discard")
+			}
+			p, err := o.newPlugin()
+			if err != nil {
+				return err
+			}
+
+			ops, err := p.UnfinishedOperations(cmd.Context(), args)
+			if err != nil {
+				return err
+			}
+			if len(ops) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No unfinished operations")
+				return nil
+			}
+
+			switch {
+			case list:
+				return plugin.PrintOperations(cmd.OutOrStdout(), ops)
+			case discard:
+				if o.dryRun != plugin.DryRunNone {
+					fmt.Fprintf(cmd.OutOrStdout(), "Would discard %d unfinished operations (dry run)\n", len(ops))
+					return nil
+				}
+				if err := p.Discard(cmd.Context(), ops); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Discarded %d unfinished operations\n", len(ops))
+				return nil
+			}
+			return o.printResults(cmd, p.Resume(cmd.Context(), ops))
+		},
+	}
+
+	cmd.Flags().BoolVar(&list, "list", false, "Only list the unfinished operations")
+	cmd.Flags().BoolVar(&discard, "discard", false, "Forget the unfinished operations without resuming them")
+	return cmd
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/futuretea/kubectl-node-maintain/pkg/plugin"
+)
+
+func newRolloutCommand(o *rootOptions) *cobra.Command {
+	var (
+		selector      string
+		readyCheck    string
+		checkInterval time.Duration
+	)
+	drainSettings := plugin.DefaultDrainSettings()
+	settings := plugin.DefaultRolloutSettings()
+
+	cmd := &cobra.Command{
+		Use:   "rollout [NODE...]",
+		Short: "Take nodes out of service for maintenance a few at a time",
+		Long: `Rolling maintenance of the given nodes, or of all nodes matching --selector.
+At most --max-unavailable nodes are cordoned and drained at once. A drained node is put back
+into service once --ready-check exits 0, or once enter is pressed when no check is given.
+It is then uncordoned and the rollout waits for it to report Ready before moving on.
+The first failed node stops the rollout and stays cordoned.
+Exits 0 when every node succeeded, 1 when the command could not run and 2 when any node failed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := o.newPlugin()
+			if err != nil {
+				return err
+			}
+
+			gate := plugin.PromptGate(cmd.InOrStdin(), cmd.ErrOrStderr())
+			if readyCheck != "" {
+				gate = plugin.CommandGate(readyCheck, checkInterval, cmd.ErrOrStderr())
+			}
+
+			results, err := p.Rollout(cmd.Context(), args, selector, drainSettings, settings, gate)
+			if err != nil {
+				return err
+			}
+			return o.printResults(cmd, results)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&selector, "selector", "l", "", "Label selector to pick nodes, e.g. node-role.kubernetes.io/worker=")
+	flags.IntVar(&settings.MaxUnavailable, "max-unavailable", settings.MaxUnavailable,
+		"Number of nodes taken out of service at the same time")
+	flags.StringVar(&readyCheck, "ready-check", "",
+		"Shell command telling whether maintenance of $NODE_NAME is done; exit 0 puts the node back into service")
+	flags.DurationVar(&checkInterval, "check-interval", 30*time.Second,
+		"How often --ready-check is run")
+	flags.DurationVar(&settings.ReadyTimeout, "ready-timeout", settings.ReadyTimeout,
+		"How long to wait for an uncordoned node to report Ready")
+	addDrainFlags(cmd, &drainSettings)
+	return cmd
+}
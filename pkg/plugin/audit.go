@@ -0,0 +1,223 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AuditEntry is one line of the audit log
+type AuditEntry struct {
+	Time    time.Time         `json:"time"`
+	Context string            `json:"context,omitempty"`
+	User    string            `json:"user"`
+	Node    string            `json:"node"`
+	Action  string            `json:"action"`
+	Options *PlanDrainOptions `json:"options,omitempty"`
+	// Delete is how the delete actions removed pods
+	Delete  *PlanDeleteOptions `json:"delete,omitempty"`
+	Changes []Change           `json:"changes,omitempty"`
+	Status  string             `json:"status"`
+	Message string             `json:"message,omitempty"`
+}
+
+// DefaultAuditLogPath is where the audit log is kept unless configured
+func DefaultAuditLogPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "node-maintain", "audit.jsonl")
+}
+
+// auditLog appends an entry for every change made to the cluster. Dry runs
+// change nothing and are not recorded.
+type auditLog struct {
+	path        string
+	kubeContext string
+	operator    *operator
+	mu          sync.Mutex
+}
+
+type auditKey struct{}
+
+// auditOptions are the options an action ran with, as far as it has any
+type auditOptions struct {
+	drain  *PlanDrainOptions
+	delete *PlanDeleteOptions
+}
+
+// withAudit attaches the audit log to ctx, so every action started with the
+// context records its changes
+func withAudit(ctx context.Context, a *auditLog) context.Context {
+	if a == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, auditKey{}, a)
+}
+
+// recordResult records the outcome of an action on a node in the audit log
+// and as an Event on the node, as far as ctx carries them. A failed audit
+// write is noted in the result, it does not undo the action.
+func recordResult(ctx context.Context, result NodeResult, options auditOptions) NodeResult {
+	nodeEvent(ctx, result)
+	return withWarning(result, writeAudit(ctx, result, options))
+}
+
+// withWarning notes a non-fatal error in the result message
+func withWarning(result NodeResult, err error) NodeResult {
+	if err != nil {
+		result.Message += fmt.Sprintf("; WARNING: %v", err)
+	}
+	return result
+}
+
+// writeAudit appends the result to the audit log of ctx, if any
+func writeAudit(ctx context.Context, result NodeResult, options auditOptions) error {
+	a, ok := ctx.Value(auditKey{}).(*auditLog)
+	if !ok {
+		return nil
+	}
+	entry := AuditEntry{
+		Time:    time.Now().UTC(),
+		Context: a.kubeContext,
+		User:    a.operator.name(),
+		Node:    result.Node,
+		Action:  result.Action,
+		Options: options.drain,
+		Delete:  options.delete,
+		Changes: result.Changes,
+		Status:  result.Status,
+		Message: result.Message,
+	}
+	if err := a.append(entry); err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	return nil
+}
+
+// operator is the user the credentials belong to, looked up once through a
+// SelfSubjectReview
+type operator struct {
+	client kubernetes.Interface
+	once   sync.Once
+	user   string
+}
+
+func (o *operator) name() string {
+	o.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		review, err := o.client.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+		if err != nil {
+			o.user = fmt.Sprintf("unknown (%v)", err)
+			return
+		}
+		o.user = review.Status.UserInfo.Username
+	})
+	return o.user
+}
+
+func (a *auditLog) append(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// AuditFilter selects audit entries; empty fields match everything
+type AuditFilter struct {
+	Node   string
+	Action string
+	User   string
+	Since  time.Time
+}
+
+func (f AuditFilter) matches(e AuditEntry) bool {
+	return (f.Node == "" || e.Node == f.Node) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.User == "" || e.User == f.User) &&
+		!e.Time.Before(f.Since)
+}
+
+// ReadAuditLog returns the entries of the audit log at path that match filter
+func ReadAuditLog(path string, filter AuditFilter) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse audit log line %d: %v", line, err)
+		}
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	return entries, nil
+}
+
+// PrintAuditEntries writes the entries as a table, or as JSON lines for
+// output "json"
+func PrintAuditEntries(w io.Writer, entries []AuditEntry, output string) error {
+	switch output {
+	case "":
+	case "json":
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q, must be json or empty", output)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tUSER\tCONTEXT\tNODE\tACTION\tRESULT\tCHANGES\tMESSAGE")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			e.Time.Local().Format(time.RFC3339), e.User, valueOrNone(e.Context), e.Node, e.Action, e.Status, len(e.Changes), e.Message)
+	}
+	return tw.Flush()
+}
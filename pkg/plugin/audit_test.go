@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node-maintain", "audit.jsonl")
+	log := &auditLog{path: path}
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	entries := []AuditEntry{
+		{Time: start, User: "alice", Node: "node-a", Action: MsgCordon, Status: ResultSucceeded},
+		{Time: start.Add(time.Hour), User: "bob", Node: "node-b", Action: MsgDrain, Status: ResultFailed},
+		{Time: start.Add(2 * time.Hour), User: "alice", Node: "node-b", Action: MsgUncordon, Status: ResultSucceeded},
+	}
+	for _, e := range entries {
+		if err := log.append(e); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   string
+	}{
+		{"everything", AuditFilter{}, "node-a,node-b,node-b"},
+		{"node", AuditFilter{Node: "node-b"}, "node-b,node-b"},
+		{"action", AuditFilter{Action: MsgDrain}, "node-b"},
+		{"user", AuditFilter{User: "alice"}, "node-a,node-b"},
+		{"since, inclusive", AuditFilter{Since: start.Add(time.Hour)}, "node-b,node-b"},
+		{"combined", AuditFilter{User: "alice", Node: "node-b"}, "node-b"},
+		{"no match", AuditFilter{Node: "node-c"}, ""},
+	}
+	for _, tt := range tests {
+		got, err := ReadAuditLog(path, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: ReadAuditLog: %v", tt.name, err)
+		}
+		nodes := make([]string, len(got))
+		for i, e := range got {
+			nodes[i] = e.Node
+		}
+		if strings.Join(nodes, ",") != tt.want {
+			t.Errorf("%s: entries for %v, want %s", tt.name, nodes, tt.want)
+		}
+	}
+}
+
+func TestReadAuditLogErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ReadAuditLog(filepath.Join(dir, "missing.jsonl"), AuditFilter{}); err == nil {
+		t.Errorf("reading a missing log succeeded")
+	}
+
+	// Blank lines are skipped but still counted
+	path := filepath.Join(dir, "audit.jsonl")
+	content := `{"node":"node-a","action":"cordon","status":"Succeeded"}` + "\n\n{not json\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ReadAuditLog(path, AuditFilter{})
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("ReadAuditLog = %v, want a parse error on line 3", err)
+	}
+}
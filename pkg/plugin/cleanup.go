@@ -0,0 +1,157 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// cleanupSettings is how finished pods are removed. Their containers have
+// stopped, so there is nothing to evict or to wait for.
+var cleanupSettings = DeleteSettings{Method: DeleteGraceful}
+
+// finishedPodSelector leaves the running pods to the API server. Evicted pods
+// are Failed, so none is lost.
+const finishedPodSelector = "status.phase!=Running,status.phase!=Pending"
+
+// isFinishedPod reports whether the pod has completed, failed or was evicted
+func isFinishedPod(pod corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed || pod.Status.Reason == "Evicted"
+}
+
+// Cleanup deletes the finished pods on the given nodes, or on every node when
+// all is set, without cordoning them. Nodes are picked either by name or by
+// label selector.
+func (p *Plugin) Cleanup(ctx context.Context, names []string, selector string, all bool) ([]NodeResult, error) {
+	ctx, flush := p.withRecorders(ctx)
+	defer flush()
+	if all {
+		if len(names) > 0 || selector != "" {
+			return nil, fmt.Errorf("cannot specify --all with node names or a selector")
+		}
+		return cleanupCluster(ctx, p.clientset, p.dryRun, nil, nil)
+	}
+	nodes, err := resolveNodes(ctx, p.clientset, names, selector)
+	if err != nil {
+		return nil, err
+	}
+	return cleanupNodes(ctx, p.clientset, nodeNames(nodes), p.dryRun, nil, nil), nil
+}
+
+// cleanupNodes deletes the finished pods node after node. Once ctx is
+// canceled the remaining nodes are left untouched.
+func cleanupNodes(ctx context.Context, clientset *kubernetes.Clientset, names []string, dryRun cmdutil.DryRunStrategy, progress progressFunc, ctrl *control) []NodeResult {
+	results := make([]NodeResult, 0, len(names))
+	for _, name := range names {
+		if ctx.Err() != nil {
+			results = append(results, canceledCleanup(name))
+			continue
+		}
+		results = append(results, cleanupFinishedPods(ctx, clientset, name, dryRun, progress, ctrl))
+	}
+	return results
+}
+
+// cleanupFinishedPods deletes the finished pods on one node
+func cleanupFinishedPods(ctx context.Context, clientset *kubernetes.Clientset, nodeName string, dryRun cmdutil.DryRunStrategy, progress progressFunc, ctrl *control) NodeResult {
+	result := NodeResult{Node: nodeName, Action: ActionCleanupFinished}
+	pods, err := listFinishedPods(ctx, clientset, fmt.Sprintf("spec.nodeName=%s", nodeName))
+	if err != nil {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("failed to get pods on node %s: %v", nodeName, err)
+		return result
+	}
+	return deletePods(ctx, clientset, result, pods[nodeName], cleanupSettings, dryRun, progress, ctrl)
+}
+
+// cleanupCluster deletes the finished pods on every node with one pod list
+// for the whole cluster. Nodes that are gone but still have pods bound to
+// them are cleaned up too.
+func cleanupCluster(ctx context.Context, clientset *kubernetes.Clientset, dryRun cmdutil.DryRunStrategy, progress progressFunc, ctrl *control) ([]NodeResult, error) {
+	nodeList, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	pods, err := listFinishedPods(ctx, clientset, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	names := nodeNames(nodeList.Items)
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+	for name := range pods {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	results := make([]NodeResult, 0, len(names))
+	for _, name := range names {
+		if ctx.Err() != nil {
+			results = append(results, canceledCleanup(name))
+			continue
+		}
+		result := NodeResult{Node: name, Action: ActionCleanupFinished}
+		results = append(results, deletePods(ctx, clientset, result, pods[name], cleanupSettings, dryRun, progress, ctrl))
+	}
+	return results, nil
+}
+
+// checkFinishedPods counts the finished pods of the cluster before a
+// cluster-wide cleanup is confirmed
+func checkFinishedPods(clientset *kubernetes.Clientset) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		pods, err := listFinishedPods(ctx, clientset, "")
+		return finishedPodsMsg{pods: pods, err: err}
+	}
+}
+
+// listFinishedPods returns the finished pods matching fieldSelector by node.
+// Pods that never got a node are left out.
+func listFinishedPods(ctx context.Context, clientset *kubernetes.Clientset, fieldSelector string) (map[string][]podInfo, error) {
+	selector := finishedPodSelector
+	if fieldSelector != "" {
+		selector = fieldSelector + "," + selector
+	}
+	podList, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	pods := make(map[string][]podInfo)
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == "" || !isFinishedPod(pod) {
+			continue
+		}
+		pods[pod.Spec.NodeName] = append(pods[pod.Spec.NodeName], podInfo{name: pod.Name, namespace: pod.Namespace, uid: pod.UID})
+	}
+	return pods, nil
+}
+
+func canceledCleanup(nodeName string) NodeResult {
+	return NodeResult{
+		Node:    nodeName,
+		Action:  ActionCleanupFinished,
+		Status:  ResultCanceled,
+		Message: "canceled before the node was touched",
+	}
+}
+
+func nodeNames(nodes []corev1.Node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestIsFinishedPod(t *testing.T) {
+	tests := []struct {
+		phase  corev1.PodPhase
+		reason string
+		want   bool
+	}{
+		{corev1.PodSucceeded, "", true},
+		{corev1.PodFailed, "", true},
+		{corev1.PodFailed, "Evicted", true},
+		// Evicted pods keep their reason even when the phase is left unset
+		{"", "Evicted", true},
+		{corev1.PodRunning, "", false},
+		{corev1.PodPending, "", false},
+		{corev1.PodUnknown, "NodeLost", false},
+	}
+	for _, tt := range tests {
+		pod := corev1.Pod{Status: corev1.PodStatus{Phase: tt.phase, Reason: tt.reason}}
+		if got := isFinishedPod(pod); got != tt.want {
+			t.Errorf("isFinishedPod(%q, %q) = %t, want %t", tt.phase, tt.reason, got, tt.want)
+		}
+	}
+}
+
+func TestClusterCleanupItems(t *testing.T) {
+	titles := func(msg finishedPodsMsg) []string {
+		var got []string
+		for _, it := range clusterCleanupItems(msg) {
+			got = append(got, it.(item).title)
+		}
+		return got
+	}
+
+	got := titles(finishedPodsMsg{pods: map[string][]podInfo{
+		"node-b": {{name: "job-1"}},
+		"node-a": {{name: "job-2"}, {name: "job-3"}},
+	}})
+	want := []string{ConfirmYes, ConfirmNo, "node-a", "node-b"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("items = %v, want %v", got, want)
+	}
+	yes := clusterCleanupItems(finishedPodsMsg{pods: map[string][]podInfo{"node-a": {{name: "job-1"}, {name: "job-2"}}}})[0].(item)
+	if yes.desc != "Confirm deleting 2 Failed, Completed and Evicted pods on 1 nodes" {
+		t.Errorf("confirmation = %q", yes.desc)
+	}
+
+	// Nothing to delete, or no count, leaves only the way back
+	for name, msg := range map[string]finishedPodsMsg{
+		"none":  {pods: map[string][]podInfo{}},
+		"error": {err: errors.New("forbidden")},
+	} {
+		for _, title := range titles(msg) {
+			if title == ConfirmYes {
+				t.Errorf("%s: offers %s", name, ConfirmYes)
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+)
+
+// control lets the TUI pause, resume and cancel a running action. A nil
+// control never pauses; cancellation then comes from the context alone.
+type control struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{}
+}
+
+func newControl(parent context.Context) *control {
+	ctx, cancel := context.WithCancel(parent)
+	return &control{ctx: ctx, cancel: cancel}
+}
+
+// togglePause pauses or resumes the action and reports whether it is now paused
+func (c *control) togglePause() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		close(c.resume)
+		c.paused = false
+	} else {
+		c.resume = make(chan struct{})
+		c.paused = true
+	}
+	return c.paused
+}
+
+// wait blocks while the action is paused and returns the error of ctx once
+// it has been canceled. It is called before each pod is handled.
+func (c *control) wait(ctx context.Context) error {
+	if c != nil {
+		c.mu.Lock()
+		paused, resume := c.paused, c.resume
+		c.mu.Unlock()
+		if paused {
+			select {
+			case <-resume:
+			case <-ctx.Done():
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// keyGate is the maintenance gate of the TUI: drained nodes wait until the
+// operator presses a key, which releases the node that has waited longest
+type keyGate struct {
+	mu      sync.Mutex
+	waiting []string
+	release map[string]chan struct{}
+}
+
+func newKeyGate() *keyGate {
+	return &keyGate{release: make(map[string]chan struct{})}
+}
+
+// wait is a MaintenanceGate blocking until the node is released
+func (g *keyGate) wait(ctx context.Context, nodeName string) error {
+	g.mu.Lock()
+	released := make(chan struct{})
+	g.waiting = append(g.waiting, nodeName)
+	g.release[nodeName] = released
+	g.mu.Unlock()
+
+	select {
+	case <-released:
+		return nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.remove(nodeName)
+		return ctx.Err()
+	}
+}
+
+// releaseNext lets the node that has waited longest go back into service
+func (g *keyGate) releaseNext() (string, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.waiting) == 0 {
+		return "", false
+	}
+	nodeName := g.waiting[0]
+	close(g.release[nodeName])
+	g.remove(nodeName)
+	return nodeName, true
+}
+
+// pending returns the nodes waiting for their maintenance, oldest first
+func (g *keyGate) pending() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.waiting...)
+}
+
+// remove forgets a node; the caller holds the lock
+func (g *keyGate) remove(nodeName string) {
+	delete(g.release, nodeName)
+	for i, name := range g.waiting {
+		if name == nodeName {
+			g.waiting = append(g.waiting[:i], g.waiting[i+1:]...)
+			return
+		}
+	}
+}
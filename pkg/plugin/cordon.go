@@ -0,0 +1,223 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+// Annotations recording who cordoned a node and why. They are removed when
+// the node is uncordoned.
+const (
+	cordonReasonAnnotation = "node-maintain.futuretea.io/cordon-reason"
+	cordonTicketAnnotation = "node-maintain.futuretea.io/cordon-ticket"
+	cordonedByAnnotation   = "node-maintain.futuretea.io/cordoned-by"
+	cordonedAtAnnotation   = "node-maintain.futuretea.io/cordoned-at"
+)
+
+// CordonNote is the reason given for cordoning a node
+type CordonNote struct {
+	Reason string
+	Ticket string
+	By     string
+	At     time.Time
+}
+
+func (n CordonNote) String() string {
+	s := n.Reason
+	if n.Ticket != "" {
+		s += fmt.Sprintf(" [%s]", n.Ticket)
+	}
+	if n.At.IsZero() {
+		return fmt.Sprintf("%s (by %s)", s, n.By)
+	}
+	return fmt.Sprintf("%s (by %s, %s ago)", s, n.By, formatDuration(time.Since(n.At)))
+}
+
+// annotations returns the note as node annotations; an empty ticket removes
+// the ticket annotation
+func (n CordonNote) annotations() map[string]interface{} {
+	annotations := map[string]interface{}{
+		cordonReasonAnnotation: n.Reason,
+		cordonTicketAnnotation: nil,
+		cordonedByAnnotation:   n.By,
+		cordonedAtAnnotation:   n.At.UTC().Format(time.RFC3339),
+	}
+	if n.Ticket != "" {
+		annotations[cordonTicketAnnotation] = n.Ticket
+	}
+	return annotations
+}
+
+// cordonNoteOf reads the cordon note of a node, if any
+func cordonNoteOf(node *corev1.Node) *CordonNote {
+	reason, ok := node.Annotations[cordonReasonAnnotation]
+	if !ok {
+		return nil
+	}
+	at, _ := time.Parse(time.RFC3339, node.Annotations[cordonedAtAnnotation])
+	return &CordonNote{
+		Reason: reason,
+		Ticket: node.Annotations[cordonTicketAnnotation],
+		By:     node.Annotations[cordonedByAnnotation],
+		At:     at,
+	}
+}
+
+// Cordon marks the given nodes unschedulable, or schedulable again when desired
+// is false. Nodes are picked either by name or by label selector.
+func (p *Plugin) Cordon(ctx context.Context, names []string, selector string, desired bool) ([]NodeResult, error) {
+	ctx, flush := p.withRecorders(ctx)
+	defer flush()
+	nodes, err := resolveNodes(ctx, p.clientset, names, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	drainer := newDrainer(p.clientset, WithContext(ctx), WithDryRunStrategy(p.dryRun))
+	return cordonNodes(drainer, nodes, desired), nil
+}
+
+// cordonNodes cordons or uncordons each node and collects the results
+func cordonNodes(drainer *drain.Helper, nodes []corev1.Node, desired bool) []NodeResult {
+	results := make([]NodeResult, 0, len(nodes))
+	for i := range nodes {
+		results = append(results, cordonNode(drainer, &nodes[i], desired, nil))
+	}
+	return results
+}
+
+// cordonNode cordons or uncordons a single node through drain.RunCordonOrUncordon.
+// A cordon stores the note on the node, if given, and an uncordon removes it
+// along with the maintenance taint.
+func cordonNode(drainer *drain.Helper, node *corev1.Node, desired bool, note *CordonNote) NodeResult {
+	result := setUnschedulable(drainer, node, desired, note)
+	if !desired && !result.Failed() {
+		result = removeMaintenanceTaint(drainer, node, result)
+	}
+	if result.Status == ResultUnchanged {
+		return result
+	}
+	return recordDone(drainer, result)
+}
+
+func setUnschedulable(drainer *drain.Helper, node *corev1.Node, desired bool, note *CordonNote) NodeResult {
+	action := MsgCordon
+	if !desired {
+		action = MsgUncordon
+	}
+	result := NodeResult{Node: node.Name, Action: action}
+
+	if node.Spec.Unschedulable == desired {
+		result.Status = ResultUnchanged
+		result.Message = fmt.Sprintf("already %sed", action)
+		return result
+	}
+
+	if drainer.DryRunStrategy != cmdutil.DryRunNone {
+		return dryRunCordon(drainer, node, desired, note, result)
+	}
+
+	result.Changes = []Change{{Action: action, Object: "node/" + node.Name}}
+	if err := drain.RunCordonOrUncordon(drainer, node, desired); err != nil {
+		result.Status = ResultFailed
+		result.Message = err.Error()
+		result.Changes[0].Error = err.Error()
+		return result
+	}
+
+	result.Status = ResultSucceeded
+	result.Message = fmt.Sprintf("%sed", action)
+	change, err := annotateCordon(drainer, node, desired, note)
+	if change != nil {
+		result.Changes = append(result.Changes, *change)
+	}
+	return withWarning(result, err)
+}
+
+// annotateCordon stores the note on a cordoned node, or removes the note of
+// an uncordoned one. Failing to do so does not undo the cordon, the error is
+// returned along with the change.
+func annotateCordon(drainer *drain.Helper, node *corev1.Node, desired bool, note *CordonNote) (*Change, error) {
+	var annotations map[string]interface{}
+	switch {
+	case desired && note != nil:
+		annotations = note.annotations()
+	case !desired && cordonNoteOf(node) != nil:
+		annotations = map[string]interface{}{
+			cordonReasonAnnotation: nil,
+			cordonTicketAnnotation: nil,
+			cordonedByAnnotation:   nil,
+			cordonedAtAnnotation:   nil,
+		}
+	default:
+		return nil, nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build annotation patch: %v", err)
+	}
+	change := &Change{Action: "annotate", Object: "node/" + node.Name, Detail: "patch " + string(patch)}
+	if drainer.DryRunStrategy == cmdutil.DryRunClient {
+		return change, nil
+	}
+	opts := metav1.PatchOptions{}
+	if drainer.DryRunStrategy == cmdutil.DryRunServer {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	if _, err := drainer.Client.CoreV1().Nodes().Patch(drainer.Ctx, node.Name, types.MergePatchType, patch, opts); err != nil {
+		change.Error = err.Error()
+		return change, fmt.Errorf("failed to annotate node %s: %v", node.Name, err)
+	}
+	return change, nil
+}
+
+// dryRunCordon records the cordon patch without persisting it. drain.RunCordonOrUncordon
+// does not honour DryRunStrategy, so the server dry run is sent through the
+// CordonHelper directly.
+func dryRunCordon(drainer *drain.Helper, node *corev1.Node, desired bool, note *CordonNote, result NodeResult) NodeResult {
+	patch, err := cordonPatch(node, desired)
+	if err != nil {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("failed to build patch: %v", err)
+		return result
+	}
+	change := Change{Action: result.Action, Object: "node/" + node.Name, Detail: "patch " + patch}
+
+	if drainer.DryRunStrategy == cmdutil.DryRunServer {
+		c := drain.NewCordonHelper(node.DeepCopy())
+		c.UpdateIfRequired(desired)
+		if err, patchErr := c.PatchOrReplaceWithContext(drainer.Ctx, drainer.Client, true); err != nil {
+			if patchErr != nil {
+				err = fmt.Errorf("%v; merge patch error: %v", err, patchErr)
+			}
+			change.Error = err.Error()
+			result.Changes = []Change{change}
+			result.Status = ResultFailed
+			result.Message = err.Error()
+			return result
+		}
+	}
+
+	// Mirror drain.RunCordonOrUncordon, which updates the node in place
+	node.Spec.Unschedulable = desired
+
+	result.Changes = []Change{change}
+	result.Status = ResultSucceeded
+	result.Message = fmt.Sprintf("would be %sed%s", result.Action, dryRunSuffix(drainer.DryRunStrategy))
+	annotation, err := annotateCordon(drainer, node, desired, note)
+	if annotation != nil {
+		result.Changes = append(result.Changes, *annotation)
+	}
+	return withWarning(result, err)
+}
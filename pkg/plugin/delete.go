@@ -0,0 +1,350 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// Methods the delete actions remove pods with
+const (
+	// DeleteEvict evicts pods through the Eviction API, respecting
+	// PodDisruptionBudgets
+	DeleteEvict = "evict"
+	// DeleteGraceful deletes pods with their own termination grace period
+	DeleteGraceful = "delete"
+	// DeleteForce deletes pods with a zero grace period
+	DeleteForce = "force"
+	// DeleteEvictThenForce evicts pods and force deletes those that are not
+	// gone by the timeout
+	DeleteEvictThenForce = "evict-then-force"
+)
+
+// DeleteMethods lists the methods in the order the TUI cycles through them
+var DeleteMethods = []string{DeleteForce, DeleteGraceful, DeleteEvict, DeleteEvictThenForce}
+
+// evictionRetryInterval is how often an eviction blocked by a disruption
+// budget is retried, the interval kubectl drain uses
+const evictionRetryInterval = 5 * time.Second
+
+// DeleteSettings holds how the delete actions remove pods
+type DeleteSettings struct {
+	Method string
+	// Timeout bounds the retries of an eviction blocked by a disruption
+	// budget; evict-then-force also waits this long for the pod to go
+	Timeout time.Duration
+}
+
+// DefaultDeleteSettings returns the settings the delete actions always had
+func DefaultDeleteSettings() DeleteSettings {
+	return DeleteSettings{Method: DeleteForce, Timeout: 2 * time.Minute}
+}
+
+// ValidateDeleteMethod returns an error for an unknown method
+func ValidateDeleteMethod(method string) error {
+	for _, m := range DeleteMethods {
+		if m == method {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown delete method %q, must be one of %s", method, strings.Join(DeleteMethods, ", "))
+}
+
+// Keys identifying the delete options shown in the TUI
+const (
+	optionDeleteMethod  = "deleteMethod"
+	optionDeleteTimeout = "deleteTimeout"
+)
+
+// deleteTimeoutChoices are the eviction timeouts cycled through in the TUI
+var deleteTimeoutChoices = []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// cycle advances an option to its next preset
+func (s *DeleteSettings) cycle(key string) {
+	switch key {
+	case optionDeleteMethod:
+		s.Method = nextChoice(DeleteMethods, s.Method)
+	case optionDeleteTimeout:
+		s.Timeout = nextChoice(deleteTimeoutChoices, s.Timeout)
+	}
+}
+
+// forceDeletePod deletes a pod with a zero grace period. A client dry run
+// skips the call, a server dry run sends it with DryRun set.
+func forceDeletePod(ctx context.Context, clientset kubernetes.Interface, pod podInfo, dryRun cmdutil.DryRunStrategy) error {
+	return deletePod(ctx, clientset, pod, new(int64), dryRun)
+}
+
+// deletePod deletes a pod with the given grace period, or with its own for
+// nil
+func deletePod(ctx context.Context, clientset kubernetes.Interface, pod podInfo, gracePeriod *int64, dryRun cmdutil.DryRunStrategy) error {
+	if dryRun == cmdutil.DryRunClient {
+		return nil
+	}
+	opts := podDeleteOptions(pod, dryRun)
+	opts.GracePeriodSeconds = gracePeriod
+	return clientset.CoreV1().Pods(pod.namespace).Delete(ctx, pod.name, *opts)
+}
+
+// evictPod evicts a pod, retrying while a disruption budget blocks it until
+// the deadline. A dry run makes a single attempt.
+func evictPod(ctx context.Context, clientset kubernetes.Interface, pod podInfo, deadline time.Time, dryRun cmdutil.DryRunStrategy) error {
+	if dryRun == cmdutil.DryRunClient {
+		return nil
+	}
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: pod.name, Namespace: pod.namespace},
+		DeleteOptions: podDeleteOptions(pod, dryRun),
+	}
+	for {
+		err := clientset.PolicyV1().Evictions(pod.namespace).Evict(ctx, eviction)
+		if err == nil || apierrors.IsNotFound(err) {
+			return nil
+		}
+		if !apierrors.IsTooManyRequests(err) || dryRun != cmdutil.DryRunNone {
+			return err
+		}
+		if time.Now().Add(evictionRetryInterval).After(deadline) {
+			return fmt.Errorf("eviction still blocked when the timeout expired: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(evictionRetryInterval):
+		}
+	}
+}
+
+// waitForPodGone waits until the pod is deleted or replaced by a pod of the
+// same name
+func waitForPodGone(ctx context.Context, clientset kubernetes.Interface, pod podInfo, deadline time.Time) error {
+	for {
+		current, err := clientset.CoreV1().Pods(pod.namespace).Get(ctx, pod.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && pod.uid != "" && current.UID != pod.uid) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("pod still terminating when the timeout expired")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// podDeleteOptions guards the deletion with the UID of the pod, so that a
+// pod recreated under the same name is left alone
+func podDeleteOptions(pod podInfo, dryRun cmdutil.DryRunStrategy) *metav1.DeleteOptions {
+	opts := &metav1.DeleteOptions{}
+	if pod.uid != "" {
+		opts.Preconditions = metav1.NewUIDPreconditions(string(pod.uid))
+	}
+	if dryRun == cmdutil.DryRunServer {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	return opts
+}
+
+// removal is how a pod was removed, or failed to be
+type removal struct {
+	method string // DeleteEvict, DeleteGraceful or DeleteForce
+	detail string
+	err    error
+}
+
+// removePod removes a pod with the method of the settings, reporting the
+// eviction or deletion to progress as it starts
+func removePod(ctx context.Context, clientset kubernetes.Interface, pod podInfo, settings DeleteSettings, dryRun cmdutil.DryRunStrategy, progress progressFunc) removal {
+	deadline := time.Now().Add(settings.Timeout)
+	switch settings.Method {
+	case DeleteEvict:
+		progress.report(pod.namespace, pod.name, PodEvicting, nil)
+		return removal{method: DeleteEvict, detail: "Eviction API", err: evictPod(ctx, clientset, pod, deadline, dryRun)}
+	case DeleteGraceful:
+		progress.report(pod.namespace, pod.name, PodDeleting, nil)
+		return removal{method: DeleteGraceful, detail: "pod grace period", err: deletePod(ctx, clientset, pod, nil, dryRun)}
+	case DeleteEvictThenForce:
+		progress.report(pod.namespace, pod.name, PodEvicting, nil)
+		err := evictPod(ctx, clientset, pod, deadline, dryRun)
+		if err == nil && dryRun == cmdutil.DryRunNone {
+			err = waitForPodGone(ctx, clientset, pod, deadline)
+		}
+		if err == nil || ctx.Err() != nil {
+			return removal{method: DeleteEvict, detail: "Eviction API", err: err}
+		}
+		progress.report(pod.namespace, pod.name, PodDeleting, nil)
+		return removal{method: DeleteForce, detail: fmt.Sprintf("grace period 0 after eviction failed: %v", err),
+			err: forceDeletePod(ctx, clientset, pod, dryRun)}
+	default:
+		progress.report(pod.namespace, pod.name, PodDeleting, nil)
+		return removal{method: DeleteForce, detail: "grace period 0", err: forceDeletePod(ctx, clientset, pod, dryRun)}
+	}
+}
+
+// deleteNonDaemonSetPods removes every pod on the node that is not managed by
+// a DaemonSet
+func deleteNonDaemonSetPods(ctx context.Context, clientset *kubernetes.Clientset, nodeName string, settings DeleteSettings, dryRun cmdutil.DryRunStrategy, progress progressFunc, ctrl *control) NodeResult {
+	result := NodeResult{Node: nodeName, Action: ActionForceDeleteNonDS}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
+	})
+	if err != nil {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("failed to get pods on node %s: %v", nodeName, err)
+		return result
+	}
+
+	var targets []podInfo
+	for _, pod := range pods.Items {
+		if !isDaemonSetPod(pod) {
+			targets = append(targets, podInfo{name: pod.Name, namespace: pod.Namespace, uid: pod.UID})
+		}
+	}
+	return deletePods(ctx, clientset, result, targets, settings, dryRun, progress, ctrl)
+}
+
+// deleteSelectedPods removes the pods picked in the TUI
+func deleteSelectedPods(ctx context.Context, clientset *kubernetes.Clientset, nodeName string, pods []podInfo, settings DeleteSettings, dryRun cmdutil.DryRunStrategy, progress progressFunc, ctrl *control) NodeResult {
+	result := NodeResult{Node: nodeName, Action: ActionForceDeleteSelected}
+	return deletePods(ctx, clientset, result, pods, settings, dryRun, progress, ctrl)
+}
+
+// deletePods removes pods one by one and records a change per pod naming the
+// method that removed it. It stops before the next pod once ctx is canceled.
+func deletePods(ctx context.Context, clientset *kubernetes.Clientset, result NodeResult, pods []podInfo, settings DeleteSettings, dryRun cmdutil.DryRunStrategy, progress progressFunc, ctrl *control) NodeResult {
+	keys := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		progress.report(pod.namespace, pod.name, PodPending, nil)
+		keys = append(keys, pod.namespace+"/"+pod.name)
+	}
+	options := auditOptions{delete: deleteOptionsOf(settings)}
+	var tracker *operationTracker
+	if dryRun == cmdutil.DryRunNone {
+		state := OperationState{Node: result.Node, Action: PlanDeletePods, Delete: deleteOptionsOf(settings)}
+		switch result.Action {
+		case ActionForceDeleteNonDS:
+			state.Action = PlanDeleteNonDaemonSetPods
+		case ActionCleanupFinished:
+			// Cleanup always deletes the same way
+			state.Action, state.Delete = PlanCleanupFinishedPods, nil
+		}
+		tracker = trackOperation(clientset, state, keys)
+		progress = tracker.wrap(progress)
+	}
+
+	failed, handled := 0, 0
+	removed := map[string]int{}
+	var auditErr error
+	for _, pod := range pods {
+		if ctrl.wait(ctx) != nil {
+			break
+		}
+		handled++
+		r := removePod(ctx, clientset, pod, settings, dryRun, progress)
+		change := Change{Action: r.method, Object: fmt.Sprintf("pod/%s/%s", pod.namespace, pod.name), Detail: r.detail}
+		status := ResultSucceeded
+		switch {
+		case r.err != nil:
+			change.Error = r.err.Error()
+			status = ResultFailed
+			failed++
+			progress.report(pod.namespace, pod.name, PodFailed, r.err)
+		case r.method == DeleteEvict:
+			removed[r.method]++
+			progress.report(pod.namespace, pod.name, PodEvicted, nil)
+		default:
+			removed[r.method]++
+			progress.report(pod.namespace, pod.name, PodDeleted, nil)
+		}
+		result.Changes = append(result.Changes, change)
+
+		// Each removal is recorded as it happens
+		if dryRun == cmdutil.DryRunNone {
+			podRemovedEvent(ctx, pod, result.Action, r)
+			if auditErr == nil {
+				auditErr = writeAudit(ctx, NodeResult{Node: result.Node, Action: result.Action, Status: status, Changes: []Change{change}}, options)
+			}
+		}
+	}
+	for _, pod := range pods[handled:] {
+		progress.report(pod.namespace, pod.name, PodSkipped, nil)
+		result.Changes = append(result.Changes, Change{
+			Action: "skip",
+			Object: fmt.Sprintf("pod/%s/%s", pod.namespace, pod.name),
+			Error:  "canceled before the pod was handled",
+		})
+	}
+	if handled < len(pods) {
+		result.Status = ResultCanceled
+		result.Message = fmt.Sprintf("canceled: %d of %d pods handled, %d failed", handled-failed, len(pods), failed)
+		return finishDeletion(ctx, tracker, result, options, dryRun, auditErr)
+	}
+
+	verb := "removed"
+	if dryRun != cmdutil.DryRunNone {
+		verb = "would be removed"
+	}
+	result.Status = ResultSucceeded
+	result.Message = fmt.Sprintf("%d pods %s%s%s", len(pods)-failed, verb, removedSummary(removed), dryRunSuffix(dryRun))
+	if failed > 0 {
+		result.Status = ResultFailed
+		result.Message += fmt.Sprintf(", %d failed", failed)
+	}
+	return finishDeletion(ctx, tracker, result, options, dryRun, auditErr)
+}
+
+// finishDeletion clears the saved progress and records the outcome for the
+// node, as an Event on the node and as a summary entry in the audit log. The
+// pods already have an audit entry each, so the summary lists no changes. A
+// deletion that found no pods changed nothing and is not recorded.
+func finishDeletion(ctx context.Context, tracker *operationTracker, result NodeResult, options auditOptions, dryRun cmdutil.DryRunStrategy, auditErr error) NodeResult {
+	result = tracker.finish(result)
+	if dryRun != cmdutil.DryRunNone || len(result.Changes) == 0 {
+		return result
+	}
+	nodeEvent(ctx, result)
+	// The per-pod entries already failed to write, no need to try again
+	if auditErr == nil {
+		summary := result
+		summary.Changes = nil
+		auditErr = writeAudit(ctx, summary, options)
+	}
+	return withWarning(result, auditErr)
+}
+
+// removedSummary counts the pods per method, e.g. " (2 evicted, 1 force deleted)"
+func removedSummary(removed map[string]int) string {
+	var parts []string
+	for _, method := range []string{DeleteEvict, DeleteGraceful, DeleteForce} {
+		if n := removed[method]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, methodVerb(method)))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// methodVerb describes what a method did to a pod
+func methodVerb(method string) string {
+	switch method {
+	case DeleteEvict:
+		return "evicted"
+	case DeleteGraceful:
+		return "deleted"
+	}
+	return "force deleted"
+}
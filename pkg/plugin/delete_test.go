@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+func TestRemovedSummary(t *testing.T) {
+	tests := []struct {
+		removed map[string]int
+		want    string
+	}{
+		{nil, ""},
+		{map[string]int{DeleteForce: 3}, " (3 force deleted)"},
+		{map[string]int{DeleteForce: 1, DeleteEvict: 2, DeleteGraceful: 0}, " (2 evicted, 1 force deleted)"},
+		{map[string]int{DeleteGraceful: 1, DeleteForce: 1, DeleteEvict: 1}, " (1 evicted, 1 deleted, 1 force deleted)"},
+	}
+	for _, tt := range tests {
+		if got := removedSummary(tt.removed); got != tt.want {
+			t.Errorf("removedSummary(%v) = %q, want %q", tt.removed, got, tt.want)
+		}
+	}
+}
+
+func TestMethodVerb(t *testing.T) {
+	for method, want := range map[string]string{
+		DeleteEvict:    "evicted",
+		DeleteGraceful: "deleted",
+		DeleteForce:    "force deleted",
+	} {
+		if got := methodVerb(method); got != want {
+			t.Errorf("methodVerb(%q) = %q, want %q", method, got, want)
+		}
+	}
+}
+
+// evictionClient returns a fake clientset holding the pod whose evictions
+// fail with evictErr, or delete the pod when it is nil
+func evictionClient(pod *corev1.Pod, evictErr error) *fake.Clientset {
+	client := fake.NewSimpleClientset(pod)
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		if evictErr != nil {
+			return true, nil, evictErr
+		}
+		return true, nil, client.Tracker().Delete(action.GetResource(), pod.Namespace, pod.Name)
+	})
+	return client
+}
+
+func TestRemovePodEvictThenForce(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", UID: "uid-1"}}
+	info := podInfo{name: pod.Name, namespace: pod.Namespace, uid: pod.UID}
+	blocked := apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 10)
+
+	tests := []struct {
+		name     string
+		evictErr error
+		method   string
+		detail   string
+	}{
+		{"evicted", nil, DeleteEvict, "Eviction API"},
+		{"blocked by a budget", blocked, DeleteForce, "grace period 0 after eviction failed: eviction still blocked"},
+		{"eviction refused", apierrors.NewForbidden(corev1.Resource("pods"), pod.Name, nil), DeleteForce, "grace period 0 after eviction failed"},
+	}
+	for _, tt := range tests {
+		client := evictionClient(pod.DeepCopy(), tt.evictErr)
+		// The timeout is shorter than the retry interval, so a blocked
+		// eviction gives up at once
+		settings := DeleteSettings{Method: DeleteEvictThenForce, Timeout: time.Second}
+		var events []string
+		progress := progressFunc(func(e podEvent) { events = append(events, e.status) })
+
+		r := removePod(context.Background(), client, info, settings, cmdutil.DryRunNone, progress)
+		if r.err != nil {
+			t.Fatalf("%s: removePod: %v", tt.name, r.err)
+		}
+		if r.method != tt.method || !strings.HasPrefix(r.detail, tt.detail) {
+			t.Errorf("%s: removal = %s (%s), want %s (%s...)", tt.name, r.method, r.detail, tt.method, tt.detail)
+		}
+		if _, err := client.CoreV1().Pods(pod.Namespace).Get(context.Background(), pod.Name, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+			t.Errorf("%s: pod still there: %v", tt.name, err)
+		}
+
+		wantEvents := []string{PodEvicting}
+		if tt.method == DeleteForce {
+			wantEvents = append(wantEvents, PodDeleting)
+			deletes := 0
+			for _, action := range client.Actions() {
+				if del, ok := action.(k8stesting.DeleteAction); ok {
+					deletes++
+					if grace := del.GetDeleteOptions().GracePeriodSeconds; grace == nil || *grace != 0 {
+						t.Errorf("%s: force delete with grace period %v", tt.name, grace)
+					}
+					if pre := del.GetDeleteOptions().Preconditions; pre == nil || pre.UID == nil || *pre.UID != pod.UID {
+						t.Errorf("%s: force delete without the UID precondition", tt.name)
+					}
+				}
+			}
+			if deletes != 1 {
+				t.Errorf("%s: %d deletes, want 1", tt.name, deletes)
+			}
+		}
+		if strings.Join(events, ",") != strings.Join(wantEvents, ",") {
+			t.Errorf("%s: progress = %v, want %v", tt.name, events, wantEvents)
+		}
+	}
+}
+
+func TestRemovePodEvictThenForceCanceled(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"}}
+	client := evictionClient(pod, apierrors.NewTooManyRequests("blocked", 10))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	settings := DeleteSettings{Method: DeleteEvictThenForce, Timeout: time.Minute}
+	r := removePod(ctx, client, podInfo{name: pod.Name, namespace: pod.Namespace}, settings, cmdutil.DryRunNone, nil)
+	if r.err == nil || r.method != DeleteEvict {
+		t.Errorf("removal = %s (%v), want the eviction to fail without a force delete", r.method, r.err)
+	}
+	if _, err := client.CoreV1().Pods(pod.Namespace).Get(context.Background(), pod.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("a canceled removal must leave the pod: %v", err)
+	}
+}
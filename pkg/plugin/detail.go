@@ -0,0 +1,225 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	resourcehelper "k8s.io/kubectl/pkg/util/resource"
+)
+
+// maxDetailEvents bounds the Events shown in the node detail view
+const maxDetailEvents = 20
+
+// nodeDetail is everything the detail view shows about one node
+type nodeDetail struct {
+	node     *corev1.Node
+	pods     int // pods that are not terminated
+	requests corev1.ResourceList
+	limits   corev1.ResourceList
+	events   []corev1.Event
+}
+
+// loadNodeDetail fetches the node with the pods running on it and its Events
+func loadNodeDetail(clientset *kubernetes.Clientset, nodeName string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		detail, err := getNodeDetail(ctx, clientset, nodeName)
+		return nodeDetailMsg{node: nodeName, detail: detail, err: err}
+	}
+}
+
+func getNodeDetail(ctx context.Context, clientset *kubernetes.Clientset, nodeName string) (nodeDetail, error) {
+	node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nodeDetail{}, fmt.Errorf("failed to get node %s: %v", nodeName, err)
+	}
+	podList, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
+	})
+	if err != nil {
+		return nodeDetail{}, fmt.Errorf("failed to get pods on node %s: %v", nodeName, err)
+	}
+
+	// Like kubectl describe node, only pods that still hold resources count
+	detail := nodeDetail{node: node, requests: corev1.ResourceList{}, limits: corev1.ResourceList{}}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		detail.pods++
+		reqs, limits := resourcehelper.PodRequestsAndLimits(pod)
+		addResources(detail.requests, reqs)
+		addResources(detail.limits, limits)
+	}
+
+	// Events are optional, the detail is still worth showing without them
+	events, err := clientset.CoreV1().Events("").List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("involvedObject.kind=Node,involvedObject.name=%s", nodeName),
+	})
+	if err == nil {
+		detail.events = events.Items
+		sort.Slice(detail.events, func(i, j int) bool {
+			return eventTime(detail.events[i]).After(eventTime(detail.events[j]))
+		})
+		if len(detail.events) > maxDetailEvents {
+			detail.events = detail.events[:maxDetailEvents]
+		}
+	}
+	return detail, nil
+}
+
+func addResources(total, add corev1.ResourceList) {
+	for name, quantity := range add {
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
+
+// eventTime returns when the Event last happened
+func eventTime(e corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	}
+	return e.CreationTimestamp.Time
+}
+
+// printNodeDetail renders the detail view in the spirit of kubectl describe node
+func printNodeDetail(w io.Writer, d nodeDetail) error {
+	node := d.node
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "Addresses:")
+	for _, a := range node.Status.Addresses {
+		fmt.Fprintf(tw, "  %s\t%s\n", a.Type, a.Address)
+	}
+
+	info := node.Status.NodeInfo
+	fmt.Fprintln(tw, "\nSystem:")
+	fmt.Fprintf(tw, "  OS\t%s\n", valueOrNone(strings.Join(strings.Fields(info.OSImage+" "+info.OperatingSystem+" "+info.Architecture), " ")))
+	fmt.Fprintf(tw, "  Kernel\t%s\n", info.KernelVersion)
+	fmt.Fprintf(tw, "  Container runtime\t%s\n", info.ContainerRuntimeVersion)
+	fmt.Fprintf(tw, "  Kubelet\t%s\n", info.KubeletVersion)
+
+	fmt.Fprintf(tw, "\nResources (%d pods):\n", d.pods)
+	fmt.Fprintln(tw, "  RESOURCE\tCAPACITY\tALLOCATABLE\tREQUESTS\tLIMITS")
+	for _, name := range resourceNames(node) {
+		capacity, allocatable := node.Status.Capacity[name], node.Status.Allocatable[name]
+		requests, limits := d.requests[name], d.limits[name]
+		if name == corev1.ResourcePods {
+			requests = *resource.NewQuantity(int64(d.pods), resource.DecimalSI)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", name, capacity.String(), allocatable.String(),
+			usage(requests, allocatable), usage(limits, allocatable))
+	}
+
+	fmt.Fprintln(tw, "\nTaints:")
+	if len(node.Spec.Taints) == 0 {
+		fmt.Fprintln(tw, "  <none>")
+	}
+	for _, t := range node.Spec.Taints {
+		fmt.Fprintf(tw, "  %s\n", t.ToString())
+	}
+
+	printMap(tw, "Labels", node.Labels)
+	printMap(tw, "Annotations", node.Annotations)
+
+	fmt.Fprintln(tw, "\nConditions:")
+	fmt.Fprintln(tw, "  TYPE\tSTATUS\tREASON\tLAST TRANSITION\tMESSAGE")
+	for _, c := range node.Status.Conditions {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", c.Type, c.Status, valueOrNone(c.Reason),
+			c.LastTransitionTime.Local().Format(time.RFC3339), c.Message)
+	}
+
+	fmt.Fprintln(tw, "\nEvents:")
+	if len(d.events) == 0 {
+		fmt.Fprintln(tw, "  <none>")
+	} else {
+		fmt.Fprintln(tw, "  AGE\tTYPE\tREASON\tFROM\tMESSAGE")
+	}
+	for _, e := range d.events {
+		from := e.Source.Component
+		if from == "" {
+			from = e.ReportingController
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", formatDuration(time.Since(eventTime(e))), e.Type, e.Reason,
+			valueOrNone(from), strings.TrimSpace(e.Message))
+	}
+	return tw.Flush()
+}
+
+// resourceNames returns the resources in the capacity of the node, the
+// common ones first
+func resourceNames(node *corev1.Node) []corev1.ResourceName {
+	common := []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage, corev1.ResourcePods}
+	names := make([]corev1.ResourceName, 0, len(node.Status.Capacity))
+	for _, name := range common {
+		if _, ok := node.Status.Capacity[name]; ok {
+			names = append(names, name)
+		}
+	}
+	var others []corev1.ResourceName
+	for name := range node.Status.Capacity {
+		if !containsResource(common, name) {
+			others = append(others, name)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i] < others[j] })
+	return append(names, others...)
+}
+
+func containsResource(names []corev1.ResourceName, name corev1.ResourceName) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// usage renders a quantity with its share of allocatable
+func usage(used, allocatable resource.Quantity) string {
+	return usageText(used.String(), percentOf(used, allocatable))
+}
+
+// printMap writes a sorted key=value section
+func printMap(w io.Writer, title string, m map[string]string) {
+	fmt.Fprintf(w, "\n%s:\n", title)
+	if len(m) == 0 {
+		fmt.Fprintln(w, "  <none>")
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %s=%s\n", k, m[k])
+	}
+}
+
+// nodeAddress returns the first address of the given type, if any
+func nodeAddress(node *corev1.Node, addressType corev1.NodeAddressType) string {
+	for _, a := range node.Status.Addresses {
+		if a.Type == addressType {
+			return a.Address
+		}
+	}
+	return ""
+}
@@ -0,0 +1,372 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+// DrainSettings holds the tunable drain options shared by the drain
+// subcommand and the TUI
+type DrainSettings struct {
+	Force                           bool
+	GracePeriodSeconds              int
+	Timeout                         time.Duration
+	DeleteEmptyDirData              bool
+	IgnoreAllDaemonSets             bool
+	PodSelector                     string
+	SkipWaitForDeleteTimeoutSeconds int
+	DisableEviction                 bool
+}
+
+// DefaultDrainSettings returns the settings matching the defaults of newDrainer
+func DefaultDrainSettings() DrainSettings {
+	return DrainSettings{
+		Force:               true,
+		GracePeriodSeconds:  -1,
+		Timeout:             30 * time.Second,
+		DeleteEmptyDirData:  true,
+		IgnoreAllDaemonSets: true,
+	}
+}
+
+// Options converts the settings into drainer options
+func (s DrainSettings) Options() []DrainerOption {
+	return []DrainerOption{
+		WithForce(s.Force),
+		WithGracePeriod(s.GracePeriodSeconds),
+		WithTimeout(s.Timeout),
+		WithDeleteEmptyDir(s.DeleteEmptyDirData),
+		WithIgnoreDaemonSets(s.IgnoreAllDaemonSets),
+		WithPodSelector(s.PodSelector),
+		WithSkipWaitForDeleteTimeout(s.SkipWaitForDeleteTimeoutSeconds),
+		WithDisableEviction(s.DisableEviction),
+	}
+}
+
+// Drain cordons and drains the given nodes one after another. Nodes are
+// picked either by name or by label selector.
+func (p *Plugin) Drain(ctx context.Context, names []string, selector string, settings DrainSettings) ([]NodeResult, error) {
+	ctx, flush := p.withRecorders(ctx)
+	defer flush()
+	nodes, err := resolveNodes(ctx, p.clientset, names, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := append(settings.Options(), WithContext(ctx), WithDryRunStrategy(p.dryRun))
+	drainer := newDrainer(p.clientset, opts...)
+	return drainNodes(drainer, nodes, nil, nil), nil
+}
+
+// drainNodes cordons and drains the nodes one after another. Once the
+// drainer context is canceled the remaining nodes are left untouched.
+func drainNodes(drainer *drain.Helper, nodes []corev1.Node, progress progressFunc, ctrl *control) []NodeResult {
+	return cordonAndRun(drainer, nodes, MsgDrain, func(node *corev1.Node) NodeResult {
+		return drainNode(drainer, node.Name, progress, ctrl)
+	})
+}
+
+// cordonAndRun cordons each node before running action on it, the way the
+// TUI does ahead of every destructive action. The cordon changes are prepended
+// to the result of action. Once the drainer context is canceled the remaining
+// nodes are left untouched.
+func cordonAndRun(drainer *drain.Helper, nodes []corev1.Node, action string, run func(node *corev1.Node) NodeResult) []NodeResult {
+	results := make([]NodeResult, 0, len(nodes))
+	for i := range nodes {
+		node := &nodes[i]
+		if drainer.Ctx.Err() != nil {
+			results = append(results, NodeResult{
+				Node:    node.Name,
+				Action:  action,
+				Status:  ResultCanceled,
+				Message: "canceled before the node was touched",
+			})
+			continue
+		}
+		cordon := cordonNode(drainer, node, true, nil)
+		if cordon.Failed() {
+			cordon.Action = action
+			results = append(results, cordon)
+			continue
+		}
+
+		result := run(node)
+		result.Changes = append(cordon.Changes, result.Changes...)
+		results = append(results, result)
+	}
+	return results
+}
+
+// maxPodsInFlight bounds the pods a pausable drain evicts at the same time
+const maxPodsInFlight = 5
+
+// drainNode evicts or deletes the pods on a cordoned node, reporting each pod
+// to progress as the drainer starts and finishes it. It follows the steps of
+// drain.RunNodeDrain but hands pods to the drainer in batches, so ctrl can
+// pause the drain between batches and a canceled context stops it before the
+// remaining pods are touched. The drain timeout is a deadline for the node.
+func drainNode(drainer *drain.Helper, nodeName string, progress progressFunc, ctrl *control) NodeResult {
+	result := NodeResult{Node: nodeName, Action: MsgDrain}
+	if drainer.DryRunStrategy != cmdutil.DryRunNone {
+		return dryRunDrain(drainer, result)
+	}
+
+	list, errs := drainer.GetPodsForDeletion(nodeName)
+	if errs != nil {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("failed to drain node: %v", utilerrors.NewAggregate(errs))
+		return result
+	}
+	if warnings := list.Warnings(); warnings != "" {
+		fmt.Fprintf(drainer.ErrOut, "WARNING: %s\n", warnings)
+	}
+	pods := list.Pods()
+	for _, pod := range pods {
+		progress.report(pod.Namespace, pod.Name, PodPending, nil)
+	}
+	tracker := trackOperation(drainer.Client, OperationState{Node: nodeName, Action: PlanDrain, Drain: drainOptionsOf(drainer)}, podKeys(pods))
+	progress = tracker.wrap(progress)
+
+	// The helper applies its timeout to each call, the node gets one deadline
+	// for all batches
+	ctx, cancel := drainer.Ctx, context.CancelFunc(func() {})
+	if drainer.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(drainer.Ctx, drainer.Timeout)
+	}
+	defer cancel()
+	helper := *drainer
+	helper.Ctx = ctx
+
+	// OnPodDeletionOrEvictionFinished supersedes the deprecated
+	// OnPodDeletedOrEvicted and also reports failures
+	var mu sync.Mutex
+	handled := 0
+	finished := make(map[string]bool)
+	helper.OnPodDeletionOrEvictionStarted = func(pod *corev1.Pod, usingEviction bool) {
+		status := PodDeleting
+		if usingEviction {
+			status = PodEvicting
+		}
+		progress.report(pod.Namespace, pod.Name, status, nil)
+	}
+	helper.OnPodDeletionOrEvictionFinished = func(pod *corev1.Pod, usingEviction bool, err error) {
+		status, action := PodDeleted, "delete"
+		if usingEviction {
+			status, action = PodEvicted, "evict"
+		}
+		change := Change{Action: action, Object: fmt.Sprintf("pod/%s/%s", pod.Namespace, pod.Name)}
+		if err != nil {
+			status = PodFailed
+			change.Error = err.Error()
+		}
+		progress.report(pod.Namespace, pod.Name, status, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		finished[change.Object] = true
+		result.Changes = append(result.Changes, change)
+		if err == nil {
+			handled++
+		}
+	}
+
+	// With a control only a few pods are in flight at once, so that a pause
+	// or cancel still leaves pods to hold back
+	batchSize := len(pods)
+	if ctrl != nil {
+		batchSize = maxPodsInFlight
+	}
+	action := "evict"
+	if drainer.DisableEviction {
+		action = "delete"
+	}
+	var drainErrs []error
+	var skipped []corev1.Pod
+	for start := 0; start < len(pods); start += batchSize {
+		if err := ctrl.wait(ctx); err != nil {
+			skipped = pods[start:]
+			break
+		}
+		batch := pods[start:min(start+batchSize, len(pods))]
+		err := helper.DeleteOrEvictPods(batch)
+		if err == nil {
+			continue
+		}
+		drainErrs = append(drainErrs, err)
+
+		// The helper only reports pods that finished waiting, failed
+		// evictions and timeouts come back as errors naming the pod
+		mu.Lock()
+		for _, pod := range batch {
+			object := fmt.Sprintf("pod/%s/%s", pod.Namespace, pod.Name)
+			if finished[object] {
+				continue
+			}
+			podErr := podError(err, pod)
+			progress.report(pod.Namespace, pod.Name, PodFailed, podErr)
+			result.Changes = append(result.Changes, Change{Action: action, Object: object, Error: podErr.Error()})
+		}
+		mu.Unlock()
+	}
+
+	for _, pod := range skipped {
+		progress.report(pod.Namespace, pod.Name, PodSkipped, nil)
+		result.Changes = append(result.Changes, Change{
+			Action: "skip",
+			Object: fmt.Sprintf("pod/%s/%s", pod.Namespace, pod.Name),
+			Error:  "canceled before the pod was handled",
+		})
+	}
+
+	options := auditOptions{drain: drainOptionsOf(drainer)}
+	if drainer.Ctx.Err() != nil && handled < len(pods) {
+		result.Status = ResultCanceled
+		result.Message = fmt.Sprintf("canceled: %d of %d pods handled", handled, len(pods))
+		return recordResult(drainer.Ctx, tracker.finish(result), options)
+	}
+	if len(drainErrs) > 0 {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("failed to drain node: %v", utilerrors.Flatten(utilerrors.NewAggregate(drainErrs)))
+		if ctx.Err() == context.DeadlineExceeded {
+			result.Message = fmt.Sprintf("failed to drain node: timed out after %s, %d of %d pods handled", drainer.Timeout, handled, len(pods))
+		}
+		return recordResult(drainer.Ctx, tracker.finish(result), options)
+	}
+	result.Status = ResultSucceeded
+	result.Message = "drained"
+	return recordResult(drainer.Ctx, tracker.finish(result), options)
+}
+
+// podError picks the error of pod out of the error of a batch. The drain
+// helper names the pod and its namespace in each message.
+func podError(err error, pod corev1.Pod) error {
+	errs := []error{err}
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		errs = utilerrors.Flatten(agg).Errors()
+	}
+	for _, e := range errs {
+		msg := e.Error()
+		if strings.Contains(msg, strconv.Quote(pod.Name)) && strings.Contains(msg, strconv.Quote(pod.Namespace)) {
+			return e
+		}
+	}
+	return fmt.Errorf("not finished: %v", err)
+}
+
+// dryRunDrain lists the pods a drain would remove. On a server dry run each
+// eviction or deletion is sent with DryRun set, so PodDisruptionBudget and
+// admission rejections show up in the report. drain.RunNodeDrain is not used
+// here because it waits for pods to disappear, which never happens on a dry run.
+func dryRunDrain(drainer *drain.Helper, result NodeResult) NodeResult {
+	list, errs := drainer.GetPodsForDeletion(result.Node)
+	if errs != nil {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("failed to drain node: %v", utilerrors.NewAggregate(errs))
+		return result
+	}
+
+	action := "delete"
+	var evictionGroupVersion schema.GroupVersion
+	if !drainer.DisableEviction {
+		gv, err := drain.CheckEvictionSupport(drainer.Client)
+		if err != nil {
+			result.Status = ResultFailed
+			result.Message = fmt.Sprintf("failed to check eviction support: %v", err)
+			return result
+		}
+		if !gv.Empty() {
+			action = "evict"
+			evictionGroupVersion = gv
+		}
+	}
+
+	failed := 0
+	pods := list.Pods()
+	for _, pod := range pods {
+		change := Change{Action: action, Object: fmt.Sprintf("pod/%s/%s", pod.Namespace, pod.Name)}
+		if drainer.DryRunStrategy == cmdutil.DryRunServer {
+			var err error
+			if action == "evict" {
+				err = drainer.EvictPod(pod, evictionGroupVersion)
+			} else {
+				err = drainer.DeletePod(pod)
+			}
+			if err != nil {
+				change.Error = err.Error()
+				failed++
+			}
+		}
+		result.Changes = append(result.Changes, change)
+	}
+
+	result.Status = ResultSucceeded
+	result.Message = fmt.Sprintf("would %s %d pods%s", action, len(pods), dryRunSuffix(drainer.DryRunStrategy))
+	if failed > 0 {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("%d of %d pods rejected%s", failed, len(pods), dryRunSuffix(drainer.DryRunStrategy))
+	}
+	if warnings := list.Warnings(); warnings != "" {
+		result.Message += "; WARNING: " + warnings
+	}
+	return result
+}
+
+// Keys identifying the drain options shown in the TUI
+const (
+	optionForce             = "force"
+	optionGracePeriod       = "gracePeriod"
+	optionTimeout           = "timeout"
+	optionDeleteEmptyDir    = "deleteEmptyDir"
+	optionIgnoreDaemonSets  = "ignoreDaemonSets"
+	optionPodSelector       = "podSelector"
+	optionSkipWaitForDelete = "skipWaitForDelete"
+	optionDisableEviction   = "disableEviction"
+)
+
+// Preset values cycled through in the TUI
+var (
+	gracePeriodChoices = []int{-1, 0, 10, 30, 60, 300}
+	timeoutChoices     = []time.Duration{30 * time.Second, time.Minute, 5 * time.Minute, 15 * time.Minute, 0}
+	skipWaitChoices    = []int{0, 10, 30, 60, 300}
+)
+
+// cycle toggles a boolean option or advances a numeric option to its next preset
+func (s *DrainSettings) cycle(key string) {
+	switch key {
+	case optionForce:
+		s.Force = !s.Force
+	case optionGracePeriod:
+		s.GracePeriodSeconds = nextChoice(gracePeriodChoices, s.GracePeriodSeconds)
+	case optionTimeout:
+		s.Timeout = nextChoice(timeoutChoices, s.Timeout)
+	case optionDeleteEmptyDir:
+		s.DeleteEmptyDirData = !s.DeleteEmptyDirData
+	case optionIgnoreDaemonSets:
+		s.IgnoreAllDaemonSets = !s.IgnoreAllDaemonSets
+	case optionSkipWaitForDelete:
+		s.SkipWaitForDeleteTimeoutSeconds = nextChoice(skipWaitChoices, s.SkipWaitForDeleteTimeoutSeconds)
+	case optionDisableEviction:
+		s.DisableEviction = !s.DisableEviction
+	}
+}
+
+// nextChoice returns the preset following current, or the first preset when
+// current is not one of them
+func nextChoice[T comparable](choices []T, current T) T {
+	for i, c := range choices {
+		if c == current {
+			return choices[(i+1)%len(choices)]
+		}
+	}
+	return choices[0]
+}
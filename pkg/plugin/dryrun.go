@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// Dry-run flag values
+const (
+	DryRunNone   = "none"
+	DryRunClient = "client"
+	DryRunServer = "server"
+)
+
+// ParseDryRunStrategy converts a --dry-run flag value into a strategy
+func ParseDryRunStrategy(value string) (cmdutil.DryRunStrategy, error) {
+	switch value {
+	case "", DryRunNone:
+		return cmdutil.DryRunNone, nil
+	case DryRunClient:
+		return cmdutil.DryRunClient, nil
+	case DryRunServer:
+		return cmdutil.DryRunServer, nil
+	}
+	return cmdutil.DryRunNone, fmt.Errorf("invalid dry-run value %q, must be %q, %q or %q",
+		value, DryRunNone, DryRunClient, DryRunServer)
+}
+
+// dryRunSuffix is appended to messages describing dry-run results
+func dryRunSuffix(strategy cmdutil.DryRunStrategy) string {
+	switch strategy {
+	case cmdutil.DryRunClient:
+		return " (dry run)"
+	case cmdutil.DryRunServer:
+		return " (server dry run)"
+	}
+	return ""
+}
+
+// cordonPatch returns the strategic merge patch that cordons or uncordons node
+func cordonPatch(node *corev1.Node, desired bool) (string, error) {
+	oldData, err := json.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+	updated := node.DeepCopy()
+	updated.Spec.Unschedulable = desired
+	newData, err := json.Marshal(updated)
+	if err != nil {
+		return "", err
+	}
+	patch, err := strategicpatch.CreateTwoWayMergePatch(oldData, newData, node)
+	if err != nil {
+		return "", err
+	}
+	return string(patch), nil
+}
+
+// PrintChanges writes every change recorded in results, one row per object
+func PrintChanges(w io.Writer, results []NodeResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tACTION\tOBJECT\tDETAIL")
+	for _, r := range results {
+		for _, c := range r.Changes {
+			detail := c.Detail
+			if c.Error != "" {
+				detail = "error: " + c.Error
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Node, c.Action, c.Object, detail)
+		}
+	}
+	return tw.Flush()
+}
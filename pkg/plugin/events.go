@@ -0,0 +1,169 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+)
+
+// eventComponent is the source of the Events the plugin records
+const eventComponent = "kubectl-node-maintain"
+
+// Event reasons of the maintenance actions. The delete actions record one
+// Event on the node and one per pod, the latter naming how the pod went.
+const (
+	EventReasonCordon             = "NodeMaintainCordon"
+	EventReasonUncordon           = "NodeMaintainUncordon"
+	EventReasonDrain              = "NodeMaintainDrain"
+	EventReasonTaint              = "NodeMaintainTaint"
+	EventReasonDeleteNonDaemonSet = "NodeMaintainDeleteNonDaemonSetPods"
+	EventReasonDeleteSelected     = "NodeMaintainDeleteSelectedPods"
+	EventReasonCleanupFinished    = "NodeMaintainCleanupFinishedPods"
+	EventReasonEvict              = "NodeMaintainEvict"
+	EventReasonDelete             = "NodeMaintainDelete"
+	EventReasonForceDelete        = "NodeMaintainForceDelete"
+	EventReasonOther              = "NodeMaintain"
+)
+
+// eventFlushTimeout bounds the wait for queued Events when an action returns
+const eventFlushTimeout = 5 * time.Second
+
+// eventRecorder records Events for the changes made to nodes and pods, so
+// they show up in kubectl describe and in tools watching Events. Events are
+// sent in the background; flush waits for them.
+type eventRecorder struct {
+	recorder record.EventRecorder
+	operator *operator
+
+	pending sync.WaitGroup
+	mu      sync.Mutex
+	err     error
+}
+
+func newEventRecorder(clientset kubernetes.Interface, op *operator) *eventRecorder {
+	broadcaster := record.NewBroadcaster()
+	r := &eventRecorder{
+		recorder: broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventComponent}),
+		operator: op,
+	}
+	// Events are written one by one instead of through StartRecordingToSink,
+	// so that flush knows when they have reached the API server
+	broadcaster.StartEventWatcher(func(event *corev1.Event) {
+		defer r.pending.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := clientset.CoreV1().Events(event.Namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+			r.mu.Lock()
+			if r.err == nil {
+				r.err = err
+			}
+			r.mu.Unlock()
+		}
+	})
+	return r
+}
+
+// event records an Event on obj naming the operator
+func (r *eventRecorder) event(obj *corev1.ObjectReference, failed bool, reason, message string) {
+	eventType := corev1.EventTypeNormal
+	if failed {
+		eventType = corev1.EventTypeWarning
+	}
+	r.pending.Add(1)
+	r.recorder.Eventf(obj, eventType, reason, "%s by %s via %s", message, r.operator.name(), eventComponent)
+}
+
+// flush waits for the queued Events and returns the first failure to record
+// one. The recorder drops Events when its queue is full, hence the timeout.
+func (r *eventRecorder) flush() error {
+	done := make(chan struct{})
+	go func() {
+		r.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(eventFlushTimeout):
+		return fmt.Errorf("timed out waiting for Events to be recorded")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.err
+	r.err = nil
+	if err != nil {
+		return fmt.Errorf("failed to record Events: %v", err)
+	}
+	return nil
+}
+
+type eventsKey struct{}
+
+// withEvents attaches the event recorder to ctx
+func withEvents(ctx context.Context, r *eventRecorder) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, eventsKey{}, r)
+}
+
+// nodeEvent records the outcome of an action on a node. The node is referred
+// to with its name as UID, like the kubelet does, so the Event shows up in
+// kubectl describe node without fetching the node.
+func nodeEvent(ctx context.Context, result NodeResult) {
+	r, ok := ctx.Value(eventsKey{}).(*eventRecorder)
+	if !ok {
+		return
+	}
+	ref := &corev1.ObjectReference{Kind: "Node", Name: result.Node, UID: types.UID(result.Node)}
+	r.event(ref, result.Failed(), eventReason(result.Action), fmt.Sprintf("%s %s: %s", result.Action, result.Status, result.Message))
+}
+
+// podRemovedEvent records the eviction or deletion of a pod
+func podRemovedEvent(ctx context.Context, pod podInfo, action string, r removal) {
+	rec, ok := ctx.Value(eventsKey{}).(*eventRecorder)
+	if !ok {
+		return
+	}
+	ref := &corev1.ObjectReference{Kind: "Pod", Namespace: pod.namespace, Name: pod.name, UID: pod.uid}
+	message := fmt.Sprintf("%s: pod %s (%s)", action, methodVerb(r.method), r.detail)
+	if r.err != nil {
+		message = fmt.Sprintf("%s: %s failed: %v", action, r.method, r.err)
+	}
+	reason := EventReasonForceDelete
+	switch r.method {
+	case DeleteEvict:
+		reason = EventReasonEvict
+	case DeleteGraceful:
+		reason = EventReasonDelete
+	}
+	rec.event(ref, r.err != nil, reason, message)
+}
+
+func eventReason(action string) string {
+	switch action {
+	case MsgCordon:
+		return EventReasonCordon
+	case MsgUncordon:
+		return EventReasonUncordon
+	case MsgDrain:
+		return EventReasonDrain
+	case MsgTaint:
+		return EventReasonTaint
+	case ActionForceDeleteNonDS:
+		return EventReasonDeleteNonDaemonSet
+	case ActionForceDeleteSelected:
+		return EventReasonDeleteSelected
+	case ActionCleanupFinished:
+		return EventReasonCleanupFinished
+	}
+	return EventReasonOther
+}
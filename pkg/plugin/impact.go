@@ -0,0 +1,302 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+	resourcehelper "k8s.io/kubectl/pkg/util/resource"
+)
+
+// mirrorPodAnnotation marks static pods, which a drain leaves alone
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// analyzeImpact simulates draining the node in the background
+func analyzeImpact(clientset *kubernetes.Clientset, nodeName string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		report, err := simulateDrain(ctx, clientset, nodeName)
+		return impactMsg{node: nodeName, report: report, err: err}
+	}
+}
+
+// simNode is a schedulable node with the capacity left after the pods
+// already running on it, and those placed on it by the simulation
+type simNode struct {
+	node *corev1.Node
+	free corev1.ResourceList
+	pods int64
+}
+
+// simPod is a pod counted for topology spread constraints
+type simPod struct {
+	namespace string
+	labels    labels.Set
+	node      string
+}
+
+// simulateDrain lists the cluster state and simulates draining the node
+func simulateDrain(ctx context.Context, clientset *kubernetes.Clientset, nodeName string) (impactReport, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return impactReport{node: nodeName}, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	})
+	if err != nil {
+		return impactReport{node: nodeName}, fmt.Errorf("failed to list pods: %v", err)
+	}
+	return simulate(nodeName, nodes.Items, pods.Items), nil
+}
+
+// simulate places the pods a drain of the node would evict onto the remaining
+// schedulable nodes, largest requests first, the way the scheduler filters
+// nodes: resource requests against allocatable, nodeSelector and required
+// node affinity, taints and tolerations, and DoNotSchedule topology spread
+// constraints. Pod affinity, priorities and preemption are not simulated.
+func simulate(nodeName string, nodes []corev1.Node, pods []corev1.Pod) impactReport {
+	report := impactReport{node: nodeName}
+
+	candidates := make(map[string]*simNode)
+	var order []*simNode
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Name == nodeName || node.Spec.Unschedulable || !isNodeReady(node) {
+			continue
+		}
+		sn := &simNode{node: node, free: node.Status.Allocatable.DeepCopy(), pods: math.MaxInt64}
+		if podCap, ok := node.Status.Allocatable[corev1.ResourcePods]; ok {
+			sn.pods = podCap.Value()
+		}
+		candidates[node.Name] = sn
+		order = append(order, sn)
+	}
+	report.candidates = len(order)
+
+	var placed []simPod
+	var movers []corev1.Pod
+	for _, pod := range pods {
+		switch pod.Spec.NodeName {
+		case "":
+		case nodeName:
+			if isDaemonSetPod(pod) || pod.Annotations[mirrorPodAnnotation] != "" {
+				continue
+			}
+			if metav1.GetControllerOf(&pod) == nil {
+				report.unmanaged = append(report.unmanaged, pod.Namespace+"/"+pod.Name)
+				continue
+			}
+			movers = append(movers, pod)
+		default:
+			placed = append(placed, simPod{namespace: pod.Namespace, labels: pod.Labels, node: pod.Spec.NodeName})
+			if sn, ok := candidates[pod.Spec.NodeName]; ok {
+				reqs, _ := resourcehelper.PodRequestsAndLimits(&pod)
+				sn.reserve(reqs)
+			}
+		}
+	}
+
+	// Place the largest pods first so small ones fill the gaps
+	requests := make(map[string]corev1.ResourceList, len(movers))
+	for i := range movers {
+		reqs, _ := resourcehelper.PodRequestsAndLimits(&movers[i])
+		requests[movers[i].Namespace+"/"+movers[i].Name] = reqs
+	}
+	sort.SliceStable(movers, func(i, j int) bool {
+		a := requests[movers[i].Namespace+"/"+movers[i].Name]
+		b := requests[movers[j].Namespace+"/"+movers[j].Name]
+		if c := a.Cpu().Cmp(*b.Cpu()); c != 0 {
+			return c > 0
+		}
+		return a.Memory().Cmp(*b.Memory()) > 0
+	})
+
+	for i := range movers {
+		pod := &movers[i]
+		key := pod.Namespace + "/" + pod.Name
+		reqs := requests[key]
+		affinity := nodeaffinity.GetRequiredNodeAffinity(pod)
+
+		var best *simNode
+		reasons := make(map[string]int)
+		for _, sn := range order {
+			reason := sn.rejects(pod, reqs, affinity)
+			if reason == "" {
+				reason = topologySpreadRejects(pod, sn, order, affinity, placed)
+			}
+			if reason != "" {
+				reasons[reason]++
+				continue
+			}
+			if best == nil || sn.free.Cpu().Cmp(*best.free.Cpu()) > 0 {
+				best = sn
+			}
+		}
+
+		if best == nil {
+			report.placements = append(report.placements, podPlacement{pod: key, reason: pendingReason(len(order), reasons)})
+			continue
+		}
+		best.reserve(reqs)
+		placed = append(placed, simPod{namespace: pod.Namespace, labels: pod.Labels, node: best.node.Name})
+		report.placements = append(report.placements, podPlacement{pod: key, node: best.node.Name})
+	}
+	return report
+}
+
+// reserve takes the requests of a pod off the free capacity of the node
+func (n *simNode) reserve(reqs corev1.ResourceList) {
+	for name, quantity := range reqs {
+		free := n.free[name]
+		free.Sub(quantity)
+		n.free[name] = free
+	}
+	n.pods--
+}
+
+// rejects returns why the pod cannot be scheduled on the node, or an empty
+// string when it fits
+func (n *simNode) rejects(pod *corev1.Pod, reqs corev1.ResourceList, affinity nodeaffinity.RequiredNodeAffinity) string {
+	if _, untolerated := corev1helpers.FindMatchingUntoleratedTaint(n.node.Spec.Taints, pod.Spec.Tolerations, func(t *corev1.Taint) bool {
+		return t.Effect == corev1.TaintEffectNoSchedule || t.Effect == corev1.TaintEffectNoExecute
+	}); untolerated {
+		return "untolerated taint"
+	}
+	if ok, _ := affinity.Match(n.node); !ok {
+		return "node selector/affinity mismatch"
+	}
+	if n.pods < 1 {
+		return "too many pods"
+	}
+	for name, quantity := range reqs {
+		if quantity.IsZero() {
+			continue
+		}
+		free, ok := n.free[name]
+		if !ok || free.Cmp(quantity) < 0 {
+			return "insufficient " + string(name)
+		}
+	}
+	return ""
+}
+
+// topologySpreadRejects checks the DoNotSchedule topology spread constraints
+// of the pod: placing it on the node must not push the domain of the node more
+// than maxSkew above the emptiest domain
+func topologySpreadRejects(pod *corev1.Pod, target *simNode, nodes []*simNode, affinity nodeaffinity.RequiredNodeAffinity, placed []simPod) string {
+	for _, c := range pod.Spec.TopologySpreadConstraints {
+		if c.WhenUnsatisfiable != corev1.DoNotSchedule {
+			continue
+		}
+		domain, ok := target.node.Labels[c.TopologyKey]
+		if !ok {
+			return "missing topology label " + c.TopologyKey
+		}
+		selector, err := metav1.LabelSelectorAsSelector(c.LabelSelector)
+		if err != nil {
+			continue
+		}
+
+		// Domains come from the nodes the pod could be placed on
+		counts := make(map[string]int)
+		nodeDomains := make(map[string]string)
+		for _, sn := range nodes {
+			if ok, _ := affinity.Match(sn.node); !ok {
+				continue
+			}
+			if value, ok := sn.node.Labels[c.TopologyKey]; ok {
+				if _, seen := counts[value]; !seen {
+					counts[value] = 0
+				}
+				nodeDomains[sn.node.Name] = value
+			}
+		}
+		for _, p := range placed {
+			value, ok := nodeDomains[p.node]
+			if ok && p.namespace == pod.Namespace && selector.Matches(p.labels) {
+				counts[value]++
+			}
+		}
+
+		minCount := -1
+		for _, count := range counts {
+			if minCount < 0 || count < minCount {
+				minCount = count
+			}
+		}
+		if c.MinDomains != nil && int(*c.MinDomains) > len(counts) {
+			minCount = 0
+		}
+		self := 0
+		if selector.Matches(labels.Set(pod.Labels)) {
+			self = 1
+		}
+		if counts[domain]+self-minCount > int(c.MaxSkew) {
+			return "topology spread " + c.TopologyKey
+		}
+	}
+	return ""
+}
+
+// pendingReason summarizes why no node fits, like the scheduler's
+// FailedScheduling event
+func pendingReason(total int, reasons map[string]int) string {
+	if total == 0 {
+		return "no schedulable nodes left"
+	}
+	parts := make([]string, 0, len(reasons))
+	for reason, count := range reasons {
+		parts = append(parts, fmt.Sprintf("%d %s", count, reason))
+	}
+	sort.Strings(parts)
+	return fmt.Sprintf("0/%d nodes available: %s", total, strings.Join(parts, ", "))
+}
+
+// isNodeReady reports whether the NodeReady condition is true
+func isNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// printImpact writes where each pod of the drained node would be rescheduled
+func printImpact(w io.Writer, r impactReport) error {
+	pending := r.pending()
+	fmt.Fprintf(w, "%d pods to reschedule onto %d schedulable nodes, %d would stay Pending\n\n",
+		len(r.placements), r.candidates, pending)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "POD\tRESULT\tNODE/REASON")
+	for _, p := range r.placements {
+		if p.node == "" {
+			fmt.Fprintf(tw, "%s\tPending\t%s\n", p.pod, p.reason)
+		} else {
+			fmt.Fprintf(tw, "%s\tScheduled\t%s\n", p.pod, p.node)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.unmanaged) > 0 {
+		fmt.Fprintf(w, "\nNot recreated after the drain (no controller): %s\n", strings.Join(r.unmanaged, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func simTestNode(name, cpu string, labels map[string]string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse("16Gi"),
+				corev1.ResourcePods:   resource.MustParse("110"),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+// simTestPod is a pod on the node owned by a ReplicaSet, requesting cpu
+func simTestPod(name, nodeName, cpu string) corev1.Pod {
+	controller := true
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			Labels:          map[string]string{"app": name},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: name + "-rs", Controller: &controller}},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Name:      "main",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+			}},
+		},
+	}
+}
+
+// placements maps each pod to its node, or to its reason when Pending
+func placements(r impactReport) map[string]string {
+	got := make(map[string]string, len(r.placements))
+	for _, p := range r.placements {
+		if p.node != "" {
+			got[p.pod] = p.node
+		} else {
+			got[p.pod] = "Pending: " + p.reason
+		}
+	}
+	return got
+}
+
+func TestSimulatePlacement(t *testing.T) {
+	cordoned := simTestNode("cordoned", "64", nil)
+	cordoned.Spec.Unschedulable = true
+	notReady := simTestNode("not-ready", "64", nil)
+	notReady.Status.Conditions[0].Status = corev1.ConditionFalse
+	nodes := []corev1.Node{simTestNode("drained", "8", nil), simTestNode("b", "4", nil), simTestNode("c", "2", nil), cordoned, notReady}
+
+	daemon := simTestPod("agent", "drained", "100m")
+	daemon.OwnerReferences[0].Kind = "DaemonSet"
+	mirror := simTestPod("etcd", "drained", "100m")
+	mirror.OwnerReferences = nil
+	mirror.Annotations = map[string]string{mirrorPodAnnotation: "hash"}
+	bare := simTestPod("debug", "drained", "100m")
+	bare.OwnerReferences = nil
+	pods := []corev1.Pod{
+		simTestPod("small", "drained", "500m"),
+		simTestPod("big", "drained", "2"),
+		simTestPod("huge", "drained", "16"),
+		simTestPod("busy", "b", "1"),
+		daemon, mirror, bare,
+	}
+
+	report := simulate("drained", nodes, pods)
+	if report.candidates != 2 {
+		t.Errorf("candidates = %d, want 2, cordoned and NotReady nodes are left out", report.candidates)
+	}
+	// The largest pods go first, each onto the node with the most free CPU
+	want := map[string]string{
+		"default/huge":  "Pending: 0/2 nodes available: 2 insufficient cpu",
+		"default/big":   "b",
+		"default/small": "c",
+	}
+	if got := placements(report); !reflect.DeepEqual(got, want) {
+		t.Errorf("placements = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(report.unmanaged, []string{"default/debug"}) {
+		t.Errorf("unmanaged = %v, want [default/debug]", report.unmanaged)
+	}
+	if report.pending() != 1 {
+		t.Errorf("pending = %d, want 1", report.pending())
+	}
+}
+
+func TestSimulateFilters(t *testing.T) {
+	tainted := simTestNode("tainted", "8", nil)
+	tainted.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}
+	full := simTestNode("full", "8", map[string]string{"disk": "ssd"})
+	full.Status.Allocatable[corev1.ResourcePods] = resource.MustParse("1")
+	nodes := []corev1.Node{simTestNode("drained", "8", nil), tainted, full, simTestNode("plain", "1", nil)}
+
+	tolerating := simTestPod("gpu-job", "drained", "2")
+	tolerating.Spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}
+	ssd := simTestPod("db", "drained", "100m")
+	ssd.Spec.NodeSelector = map[string]string{"disk": "ssd"}
+	pods := []corev1.Pod{
+		tolerating,
+		simTestPod("web", "drained", "2"),
+		ssd,
+		simTestPod("filler", "full", "100m"),
+	}
+
+	want := map[string]string{
+		"default/gpu-job": "tainted",
+		"default/web":     "Pending: 0/3 nodes available: 1 insufficient cpu, 1 too many pods, 1 untolerated taint",
+		"default/db":      "Pending: 0/3 nodes available: 1 node selector/affinity mismatch, 1 too many pods, 1 untolerated taint",
+	}
+	if got := placements(simulate("drained", nodes, pods)); !reflect.DeepEqual(got, want) {
+		t.Errorf("placements = %v, want %v", got, want)
+	}
+}
+
+func TestSimulateTopologySpread(t *testing.T) {
+	zone := func(name, value string) corev1.Node {
+		return simTestNode(name, "8", map[string]string{"topology.kubernetes.io/zone": value})
+	}
+	// Zone a has more free CPU, but already runs a replica
+	nodes := []corev1.Node{zone("drained", "b"), zone("a-1", "a"), zone("b-1", "b")}
+	nodes[1].Status.Allocatable[corev1.ResourceCPU] = resource.MustParse("32")
+
+	spread := func(name, nodeName string) corev1.Pod {
+		pod := simTestPod(name, nodeName, "1")
+		pod.Labels = map[string]string{"app": "web"}
+		pod.Spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		}}
+		return pod
+	}
+	pods := []corev1.Pod{spread("web-1", "a-1"), spread("web-2", "a-1"), spread("web-3", "drained")}
+
+	want := map[string]string{"default/web-3": "b-1"}
+	if got := placements(simulate("drained", nodes, pods)); !reflect.DeepEqual(got, want) {
+		t.Errorf("placements = %v, want %v", got, want)
+	}
+}
+
+func TestSimulateNoCandidates(t *testing.T) {
+	nodes := []corev1.Node{simTestNode("drained", "8", nil)}
+	pods := []corev1.Pod{simTestPod("web", "drained", "1")}
+	want := map[string]string{"default/web": "Pending: no schedulable nodes left"}
+	if got := placements(simulate("drained", nodes, pods)); !reflect.DeepEqual(got, want) {
+		t.Errorf("placements = %v, want %v", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/drain"
 )
 
@@ -53,63 +55,179 @@ func WithIgnoreDaemonSets(ignore bool) DrainerOption {
 	}
 }
 
+// WithPodSelector sets the label selector used to filter pods on the node
+func WithPodSelector(selector string) DrainerOption {
+	return func(h *drain.Helper) {
+		h.PodSelector = selector
+	}
+}
+
+// WithSkipWaitForDeleteTimeout sets the SkipWaitForDeleteTimeoutSeconds
+func WithSkipWaitForDeleteTimeout(seconds int) DrainerOption {
+	return func(h *drain.Helper) {
+		h.SkipWaitForDeleteTimeoutSeconds = seconds
+	}
+}
+
+// WithDisableEviction sets the DisableEviction option
+func WithDisableEviction(disable bool) DrainerOption {
+	return func(h *drain.Helper) {
+		h.DisableEviction = disable
+	}
+}
+
+// WithDryRunStrategy sets the DryRunStrategy
+func WithDryRunStrategy(strategy cmdutil.DryRunStrategy) DrainerOption {
+	return func(h *drain.Helper) {
+		h.DryRunStrategy = strategy
+	}
+}
+
+// WithOutput sets where the drainer writes its progress and warnings
+func WithOutput(out, errOut io.Writer) DrainerOption {
+	return func(h *drain.Helper) {
+		h.Out = out
+		h.ErrOut = errOut
+	}
+}
+
+// WithContext sets the context used for API calls made by the drainer
+func WithContext(ctx context.Context) DrainerOption {
+	return func(h *drain.Helper) {
+		h.Ctx = ctx
+	}
+}
+
 // getNodes retrieves the list of nodes from the cluster
 // Returns a tea.Cmd that will fetch the nodes asynchronously
 func getNodes(clientset *kubernetes.Clientset) tea.Cmd {
 	return func() tea.Msg {
-		if clientset == nil {
-			return fmt.Errorf("kubernetes client is not initialized")
-		}
-
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
-		nodeList, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		nodes, err := listNodes(ctx, clientset, "")
 		if err != nil {
 			return err
 		}
 
-		if len(nodeList.Items) == 0 {
+		if len(nodes) == 0 {
 			return fmt.Errorf("no nodes found in the cluster")
 		}
+		return nodesMsg(nodes)
+	}
+}
 
-		var nodes []nodeInfo
-		for _, node := range nodeList.Items {
-			ready := "NotReady"
-			var conditions []string
-			for _, condition := range node.Status.Conditions {
-				if condition.Status == corev1.ConditionTrue {
-					conditions = append(conditions, string(condition.Type))
-				}
-				if condition.Type == corev1.NodeReady {
-					if condition.Status == corev1.ConditionTrue {
-						ready = "Ready"
-					}
-					break
-				}
+// discardOperations forgets the unfinished operations and reloads the nodes
+func discardOperations(clientset *kubernetes.Clientset, ops []OperationState) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for _, op := range ops {
+			if err := clearOperation(ctx, clientset, op.Node); err != nil {
+				return err
 			}
+		}
+		return getNodes(clientset)()
+	}
+}
+
+// listNodes lists the nodes matching selector and converts them for display
+func listNodes(ctx context.Context, clientset *kubernetes.Clientset, selector string) ([]nodeInfo, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client is not initialized")
+	}
+
+	nodeList, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]nodeInfo, 0, len(nodeList.Items))
+	for _, node := range nodeList.Items {
+		nodes = append(nodes, newNodeInfo(node))
+	}
+	return nodes, nil
+}
 
-			// Get node roles
-			roles := getNodeRoles(node.Labels)
-			if len(roles) == 0 {
-				roles = []string{"<none>"}
+// newNodeInfo extracts the fields shown for a node
+func newNodeInfo(node corev1.Node) nodeInfo {
+	ready := "NotReady"
+	var conditions []string
+	for _, condition := range node.Status.Conditions {
+		if condition.Status == corev1.ConditionTrue {
+			conditions = append(conditions, string(condition.Type))
+		}
+		if condition.Type == corev1.NodeReady {
+			if condition.Status == corev1.ConditionTrue {
+				ready = "Ready"
 			}
+			break
+		}
+	}
+
+	// Get node roles
+	roles := getNodeRoles(node.Labels)
+	if len(roles) == 0 {
+		roles = []string{"<none>"}
+	}
+
+	// Get age
+	age := time.Since(node.CreationTimestamp.Time).Round(time.Second)
+
+	var taint string
+	if t := maintenanceTaintOf(&node); t != nil && hasTaint(&node, t) {
+		taint = t.ToString()
+	}
 
-			// Get age
-			age := time.Since(node.CreationTimestamp.Time).Round(time.Second)
-
-			nodes = append(nodes, nodeInfo{
-				name:        node.Name,
-				status:      ready,
-				schedulable: !node.Spec.Unschedulable,
-				roles:       roles,
-				age:         age,
-				version:     node.Status.NodeInfo.KubeletVersion,
-				internal:    node.Status.Addresses[0].Address,
-				conditions:  conditions,
-			})
+	return nodeInfo{
+		name:        node.Name,
+		status:      ready,
+		schedulable: !node.Spec.Unschedulable,
+		roles:       roles,
+		age:         age,
+		version:     node.Status.NodeInfo.KubeletVersion,
+		internal:    nodeAddress(&node, corev1.NodeInternalIP),
+		conditions:  conditions,
+		operation:   operationOf(&node),
+		cordon:      cordonNoteOf(&node),
+		taint:       taint,
+		labels:      node.Labels,
+
+		allocatableCPU:    node.Status.Allocatable[corev1.ResourceCPU],
+		allocatableMemory: node.Status.Allocatable[corev1.ResourceMemory],
+		podCount:          -1,
+	}
+}
+
+// resolveNodes looks up the nodes to operate on, either by name or by label
+// selector. All named nodes must exist before any of them is touched.
+func resolveNodes(ctx context.Context, clientset *kubernetes.Clientset, names []string, selector string) ([]corev1.Node, error) {
+	if len(names) > 0 && selector != "" {
+		return nil, fmt.Errorf("cannot specify both node names and a selector")
+	}
+	if len(names) == 0 && selector == "" {
+		return nil, fmt.Errorf("at least one node name or a selector is required")
+	}
+
+	if selector != "" {
+		nodeList, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list nodes: %v", err)
 		}
-		return nodesMsg(nodes)
+		if len(nodeList.Items) == 0 {
+			return nil, fmt.Errorf("no nodes match selector %q", selector)
+		}
+		return nodeList.Items, nil
+	}
+
+	nodes := make([]corev1.Node, 0, len(names))
+	for _, name := range names {
+		node, err := clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node %s: %v", name, err)
+		}
+		nodes = append(nodes, *node)
 	}
+	return nodes, nil
 }
 
 func getNodeRoles(labels map[string]string) []string {
@@ -126,30 +244,77 @@ func getNodeRoles(labels map[string]string) []string {
 
 func getPods(clientset *kubernetes.Clientset, nodeName string) tea.Cmd {
 	return func() tea.Msg {
-		podList, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
-			FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
-		})
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		pods, err := listPods(ctx, clientset, nodeName)
 		if err != nil {
 			return err
 		}
-		var pods []podInfo
-		for _, pod := range podList.Items {
-			var owner, ownerKind string
-			if len(pod.OwnerReferences) > 0 {
-				owner = pod.OwnerReferences[0].Name
-				ownerKind = pod.OwnerReferences[0].Kind
-			}
+		return podsMsg{node: nodeName, pods: pods}
+	}
+}
 
-			pods = append(pods, podInfo{
-				name:      pod.Name,
-				namespace: pod.Namespace,
-				owner:     owner,
-				ownerKind: ownerKind,
-				phase:     string(pod.Status.Phase),
-				age:       time.Since(pod.CreationTimestamp.Time),
-			})
+// listPods lists the pods scheduled on a node and converts them for display
+func listPods(ctx context.Context, clientset *kubernetes.Clientset, nodeName string) ([]podInfo, error) {
+	podList, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]podInfo, 0, len(podList.Items))
+	for _, pod := range podList.Items {
+		pods = append(pods, newPodInfo(pod))
+	}
+	return pods, nil
+}
+
+// runningPodSelector leaves out the pods that terminated
+const runningPodSelector = "status.phase!=Succeeded,status.phase!=Failed"
+
+// countPods returns the number of pods that are not terminated by node
+func countPods(ctx context.Context, clientset *kubernetes.Clientset) (map[string]int, error) {
+	// Resource version 0 is served from the API server cache
+	podList, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		ResourceVersion: "0",
+		FieldSelector:   runningPodSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	pods := make([]*corev1.Pod, 0, len(podList.Items))
+	for i := range podList.Items {
+		pods = append(pods, &podList.Items[i])
+	}
+	return podCounts(pods), nil
+}
+
+// applyPodCounts sets the pod count of the nodes, unknown without counts
+func applyPodCounts(nodes []nodeInfo, counts map[string]int) {
+	for i := range nodes {
+		nodes[i].podCount = -1
+		if counts != nil {
+			nodes[i].podCount = counts[nodes[i].name]
 		}
-		return podsMsg(pods)
+	}
+}
+
+// newPodInfo extracts the fields shown for a pod
+func newPodInfo(pod corev1.Pod) podInfo {
+	var owner, ownerKind string
+	if len(pod.OwnerReferences) > 0 {
+		owner = pod.OwnerReferences[0].Name
+		ownerKind = pod.OwnerReferences[0].Kind
+	}
+
+	return podInfo{
+		name:      pod.Name,
+		namespace: pod.Namespace,
+		uid:       pod.UID,
+		owner:     owner,
+		ownerKind: ownerKind,
+		phase:     string(pod.Status.Phase),
+		age:       time.Since(pod.CreationTimestamp.Time),
 	}
 }
 
@@ -176,28 +341,15 @@ func newDrainer(clientset *kubernetes.Clientset, opts ...DrainerOption) *drain.H
 }
 
 func getNode(clientset *kubernetes.Clientset, nodeName string) (*corev1.Node, error) {
-	node, err := clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
 	return node, nil
 }
 
-func deletePod(clientset *kubernetes.Clientset, namespace, name string) tea.Cmd {
-	return func() tea.Msg {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-		err := clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{
-			GracePeriodSeconds: new(int64),
-		})
-		if err != nil {
-			return fmt.Errorf("failed to delete pod %s/%s: %v", namespace, name, err)
-		}
-		fmt.Printf("Successfully deleted pod %s/%s\n", namespace, name)
-		return nil
-	}
-}
-
 func isDaemonSetPod(pod corev1.Pod) bool {
 	for _, owner := range pod.OwnerReferences {
 		if owner.Kind == "DaemonSet" {
@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"context"
+)
+
+// ListNodes returns the nodes matching filter as shown in the TUI node list,
+// ordered by sortBy. Usage is left out when metrics are not available, pod
+// counts unless withPods is set or the nodes are sorted by them.
+func (p *Plugin) ListNodes(ctx context.Context, filter NodeFilter, sortBy string, withPods bool) ([]NodeSummary, error) {
+	if err := ValidateNodeSort(sortBy); err != nil {
+		return nil, err
+	}
+	nodes, err := listNodes(ctx, p.clientset, filter.labelSelector())
+	if err != nil {
+		return nil, err
+	}
+	if usage, err := fetchNodeUsage(ctx, p.clientset); err == nil {
+		applyNodeUsage(nodes, usage)
+	}
+	if withPods || sortBy == SortByPods {
+		if counts, err := countPods(ctx, p.clientset); err == nil {
+			applyPodCounts(nodes, counts)
+		}
+	}
+	nodes = filter.filterNodes(nodes)
+	sortNodes(nodes, defaultSort(sortBy))
+	summaries := make([]NodeSummary, 0, len(nodes))
+	for _, n := range nodes {
+		summaries = append(summaries, n.summary())
+	}
+	return summaries, nil
+}
+
+// ListPods returns the pods on a node as shown in the TUI pod picker
+func (p *Plugin) ListPods(ctx context.Context, nodeName string) ([]PodSummary, error) {
+	pods, err := listPods(ctx, p.clientset, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	if usage, err := fetchPodUsage(ctx, p.clientset); err == nil {
+		applyPodUsage(pods, usage)
+	}
+	summaries := make([]PodSummary, 0, len(pods))
+	for _, pod := range pods {
+		summary := pod.summary()
+		summary.Node = nodeName
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+func (n nodeInfo) summary() NodeSummary {
+	return NodeSummary{
+		Name:        n.name,
+		Status:      n.status,
+		Schedulable: n.schedulable,
+		Roles:       n.roles,
+		Age:         formatDuration(n.age),
+		Version:     n.version,
+		InternalIP:  n.internal,
+		Conditions:  n.conditions,
+		Usage:       n.usageSummary(),
+		Pods:        n.podCountSummary(),
+	}
+}
+
+func (n nodeInfo) podCountSummary() *int {
+	if n.podCount < 0 {
+		return nil
+	}
+	count := n.podCount
+	return &count
+}
+
+func (n nodeInfo) usageSummary() *Usage {
+	if n.usage == nil {
+		return nil
+	}
+	usage := &Usage{CPU: formatCPU(n.usage.cpu), Memory: formatMemory(n.usage.memory)}
+	if percent := n.usagePercent(SortByCPU); percent >= 0 {
+		usage.CPUPercent = &percent
+	}
+	if percent := n.usagePercent(SortByMemory); percent >= 0 {
+		usage.MemoryPercent = &percent
+	}
+	return usage
+}
+
+func (p podInfo) summary() PodSummary {
+	return PodSummary{
+		Name:      p.name,
+		Namespace: p.namespace,
+		Owner:     p.owner,
+		OwnerKind: p.ownerKind,
+		Phase:     p.phase,
+		Age:       formatDuration(p.age),
+		Usage:     p.usageSummary(),
+	}
+}
+
+func (p podInfo) usageSummary() *Usage {
+	if p.usage == nil {
+		return nil
+	}
+	return &Usage{CPU: formatCPU(p.usage.cpu), Memory: formatMemory(p.usage.memory)}
+}
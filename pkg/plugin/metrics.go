@@ -0,0 +1,182 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// metricsInterval is how often usage is refreshed, about the resolution of
+// metrics-server
+const metricsInterval = 30 * time.Second
+
+// metricsPath is the metrics.k8s.io API served by metrics-server
+const metricsPath = "/apis/metrics.k8s.io/v1beta1"
+
+// resourceUsage is the CPU and memory in use by a node or a pod
+type resourceUsage struct {
+	cpu    resource.Quantity
+	memory resource.Quantity
+}
+
+// nodeMetricsList and podMetricsList decode the parts of the metrics.k8s.io
+// lists the plugin uses, without depending on k8s.io/metrics
+type nodeMetricsList struct {
+	Items []struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Usage             corev1.ResourceList `json:"usage"`
+	} `json:"items"`
+}
+
+type podMetricsList struct {
+	Items []struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Containers        []struct {
+			Usage corev1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// metricsMsg carries the usage of all nodes and, when a pod list is shown,
+// of all pods keyed by namespace/name. err is set when the metrics API is
+// missing or failing; the lists then show no usage.
+type metricsMsg struct {
+	nodes map[string]resourceUsage
+	pods  map[string]resourceUsage
+	err   error
+}
+
+type metricsTickMsg struct{}
+
+// getMetrics fetches node usage, and pod usage too when withPods is set
+func getMetrics(clientset *kubernetes.Clientset, withPods bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		nodes, err := fetchNodeUsage(ctx, clientset)
+		if err != nil {
+			return metricsMsg{err: err}
+		}
+		msg := metricsMsg{nodes: nodes}
+		if withPods {
+			msg.pods, msg.err = fetchPodUsage(ctx, clientset)
+		}
+		return msg
+	}
+}
+
+// metricsTick schedules the next metrics refresh
+func metricsTick() tea.Cmd {
+	return tea.Tick(metricsInterval, func(time.Time) tea.Msg { return metricsTickMsg{} })
+}
+
+// fetchNodeUsage returns the usage of every node by name
+func fetchNodeUsage(ctx context.Context, clientset *kubernetes.Clientset) (map[string]resourceUsage, error) {
+	var list nodeMetricsList
+	if err := getMetricsList(ctx, clientset, "nodes", &list); err != nil {
+		return nil, err
+	}
+	usage := make(map[string]resourceUsage, len(list.Items))
+	for _, item := range list.Items {
+		usage[item.Name] = resourceUsage{cpu: item.Usage[corev1.ResourceCPU], memory: item.Usage[corev1.ResourceMemory]}
+	}
+	return usage, nil
+}
+
+// fetchPodUsage returns the usage of every pod, summed over its containers,
+// by namespace/name. Pod metrics cannot be selected by node.
+func fetchPodUsage(ctx context.Context, clientset *kubernetes.Clientset) (map[string]resourceUsage, error) {
+	var list podMetricsList
+	if err := getMetricsList(ctx, clientset, "pods", &list); err != nil {
+		return nil, err
+	}
+	usage := make(map[string]resourceUsage, len(list.Items))
+	for _, item := range list.Items {
+		var u resourceUsage
+		for _, c := range item.Containers {
+			u.cpu.Add(c.Usage[corev1.ResourceCPU])
+			u.memory.Add(c.Usage[corev1.ResourceMemory])
+		}
+		usage[item.Namespace+"/"+item.Name] = u
+	}
+	return usage, nil
+}
+
+func getMetricsList(ctx context.Context, clientset *kubernetes.Clientset, kind string, into interface{}) error {
+	if clientset == nil {
+		return fmt.Errorf("kubernetes client is not initialized")
+	}
+	data, err := clientset.Discovery().RESTClient().Get().AbsPath(metricsPath, kind).DoRaw(ctx)
+	if err != nil {
+		return fmt.Errorf("metrics API not available: %v", err)
+	}
+	if err := json.Unmarshal(data, into); err != nil {
+		return fmt.Errorf("failed to decode %s metrics: %v", kind, err)
+	}
+	return nil
+}
+
+// applyNodeUsage sets the usage of the nodes found in usage and clears it on
+// the others
+func applyNodeUsage(nodes []nodeInfo, usage map[string]resourceUsage) {
+	for i := range nodes {
+		nodes[i].usage = nil
+		if u, ok := usage[nodes[i].name]; ok {
+			nodes[i].usage = &u
+		}
+	}
+}
+
+// applyPodUsage sets the usage of the pods found in usage and clears it on
+// the others
+func applyPodUsage(pods []podInfo, usage map[string]resourceUsage) {
+	for i := range pods {
+		pods[i].usage = nil
+		if u, ok := usage[pods[i].namespace+"/"+pods[i].name]; ok {
+			pods[i].usage = &u
+		}
+	}
+}
+
+// usagePercent returns the CPU or memory usage of the node as a share of
+// allocatable, -1 when unknown
+func (n nodeInfo) usagePercent(resourceName string) int64 {
+	if n.usage == nil {
+		return -1
+	}
+	if resourceName == SortByCPU {
+		return percentOf(n.usage.cpu, n.allocatableCPU)
+	}
+	return percentOf(n.usage.memory, n.allocatableMemory)
+}
+
+func percentOf(used, allocatable resource.Quantity) int64 {
+	if allocatable.IsZero() {
+		return -1
+	}
+	return int64(float64(used.MilliValue()) / float64(allocatable.MilliValue()) * 100)
+}
+
+// formatCPU and formatMemory render usage the way kubectl top does
+func formatCPU(q resource.Quantity) string {
+	return fmt.Sprintf("%dm", q.MilliValue())
+}
+
+func formatMemory(q resource.Quantity) string {
+	return fmt.Sprintf("%dMi", q.Value()/(1024*1024))
+}
+
+// usageText renders usage with its share of allocatable when known
+func usageText(value string, percent int64) string {
+	if percent < 0 {
+		return value
+	}
+	return fmt.Sprintf("%s (%d%%)", value, percent)
+}
@@ -2,20 +2,26 @@ package plugin
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"golang.org/x/term"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/kubectl/pkg/drain"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 )
 
-func initialModel(clientset *kubernetes.Clientset) model {
+func initialModel(ctx context.Context, p *Plugin) model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
@@ -29,32 +35,86 @@ func initialModel(clientset *kubernetes.Clientset) model {
 
 	l := createList([]list.Item{}, "Loading nodes...", w, h)
 
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Cursor.SetMode(cursor.CursorStatic)
+
 	return model{
-		spinner:      s,
-		list:         l,
-		width:        w,
-		height:       h,
-		state:        StateSelectNode,
-		clientset:    clientset,
-		confirm:      false,
-		selectedPods: make(map[string]podInfo),
+		spinner:          s,
+		list:             l,
+		width:            w,
+		height:           h,
+		state:            StateSelectNode,
+		clientset:        p.clientset,
+		confirm:          false,
+		selectedPods:     make(map[string]podInfo),
+		selectedNodes:    make(map[string]nodeInfo),
+		drainSettings:    p.drainSettings,
+		deleteSettings:   p.deleteSettings,
+		rolloutSettings:  DefaultRolloutSettings(),
+		dryRun:           p.dryRun,
+		watcher:          newWatcher(p.clientset),
+		input:            input,
+		ctx:              ctx,
+		operator:         p.operator,
+		maintenanceTaint: p.maintenanceTaint,
+		maintenanceMode:  ModeCordon,
+		nodeSort:         defaultSort(SortByName),
+		podSort:          tableSort{column: "namespace"},
+		nodeFilter:       p.nodeFilter,
+		nodeIndex:        &nodeIndex{},
 	}
 }
 
 func (m model) Init() tea.Cmd {
 	return tea.Sequence(
 		tea.EnterAltScreen,
-		tea.Batch(m.spinner.Tick, getNodes(m.clientset)),
+		tea.Batch(m.spinner.Tick, getNodes(m.clientset), getMetrics(m.clientset, false), metricsTick()),
 	)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.state == StateEditOption {
+			return m.updateEditOption(msg)
+		}
+		if m.state == StateCordonReason {
+			return m.updateCordonReason(msg)
+		}
+		// Keys stop or pause a running action instead of leaving it behind
+		if m.state == StateProgress && !m.progress.done {
+			return m.updateProgress(msg)
+		}
 		if msg.String() == "ctrl+c" || msg.String() == "q" {
 			m.quitting = true
 			return m, tea.Sequence(tea.ExitAltScreen, tea.Quit)
 		}
+		if m.state == StateReport {
+			return m.updateReport(msg)
+		}
+		if m.state == StateImpact {
+			return m.updateImpact(msg)
+		}
+		if m.state == StateNodeDetail {
+			return m.updateNodeDetail(msg)
+		}
+		// While a pod filter is typed every key belongs to the filter, and
+		// enter only applies it
+		if m.state == StateSelectPods && m.list.FilterState() == list.Filtering {
+			var cmd tea.Cmd
+			m.list, cmd = m.list.Update(msg)
+			return m, cmd
+		}
+		// d also pages the list, so it is handled before the list sees it
+		if m.state == StateSelectNode && msg.String() == KeyD && m.list.FilterState() != list.Filtering {
+			if node, ok := m.list.SelectedItem().(nodeInfo); ok {
+				return m.showNodeDetail(node)
+			}
+		}
+		if m.state == StateProgress {
+			return m.updateProgress(msg)
+		}
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -64,28 +124,216 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		h, v := lipgloss.NewStyle().Margin(1, 2).GetFrameSize()
 		m.list.SetSize(m.width-h, m.height-v)
+		m.report.Width = m.width - h
+		m.report.Height = m.height - v - reportChrome
+		m.progress.setSize(m.width, m.height)
+		m.applyListView()
 
 	case error:
 		m.err = msg
 		return m, nil
 
+	case interruptMsg:
+		// The operation context derives from m.ctx and is already canceled;
+		// quit once the running action has reported what it handled
+		if m.state == StateProgress && !m.progress.done {
+			m.quitAfterOp = true
+			m.progress.canceling = true
+			return m, nil
+		}
+		m.quitting = true
+		return m, tea.Sequence(tea.ExitAltScreen, tea.Quit)
+
 	case nodesMsg:
-		items := make([]list.Item, 0, len(msg))
-		for _, node := range msg {
+		// Watch updates may arrive while another screen is shown
+		if m.state != StateSelectNode {
+			return m, nil
+		}
+		// Offer once to pick up operations an earlier session did not finish
+		if !m.resumeOffered {
+			m.resumeOffered = true
+			m.unfinished = nil
+			for _, node := range msg {
+				if node.operation != nil {
+					m.unfinished = append(m.unfinished, *node.operation)
+				}
+			}
+			if len(m.unfinished) > 0 {
+				m.state = StateResume
+				m.list = createList(resumeItems(m.unfinished), "Unfinished Operations", m.width, m.height)
+				return m, nil
+			}
+		}
+		applyNodeUsage(msg, m.nodeUsage)
+		applyPodCounts(msg, m.podCounts)
+		nodes := m.nodeFilter.filterNodes(msg)
+		sortNodes(nodes, m.nodeSort)
+		m.nodeIndex.set(nodes)
+		// Carry selections over, dropping nodes that no longer exist
+		current := make(map[string]bool, len(nodes))
+		items := make([]list.Item, 0, len(nodes))
+		for _, node := range nodes {
+			current[node.name] = true
+			if _, ok := m.selectedNodes[node.name]; ok {
+				node.selected = true
+				m.selectedNodes[node.name] = node
+			}
 			items = append(items, node)
 		}
+		for name := range m.selectedNodes {
+			if !current[name] {
+				delete(m.selectedNodes, name)
+			}
+		}
 
-		m.list = createList(items, "Select Node", m.width, m.height)
+		if _, ok := m.list.SelectedItem().(nodeInfo); ok {
+			return m, setItemsKeepCursor(&m.list, items)
+		}
+		m.list = createList(items, m.nodeListTitle(), m.width, m.height)
+		m.list.Filter = m.nodeIndex.filter
+		m.applyListView()
+		return m, nil
+
+	case metricsMsg:
+		// Without the metrics API the lists simply show no usage
+		m.nodeUsage, m.podUsage = msg.nodes, msg.pods
+		switch m.state {
+		case StateSelectNode:
+			if _, ok := m.list.SelectedItem().(nodeInfo); ok {
+				return m, m.refreshNodes()
+			}
+		case StateSelectPods:
+			if _, ok := m.list.SelectedItem().(podInfo); ok {
+				return m, m.refreshPods()
+			}
+		}
+		return m, nil
+
+	case podCountsMsg:
+		// A count that arrives after the watch stopped is stale
+		if !m.needsPodCounts() {
+			return m, nil
+		}
+		m.podCounts = msg.counts
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Pod counts unknown: %v", msg.err)
+		}
+		if _, ok := m.list.SelectedItem().(nodeInfo); ok && m.state == StateSelectNode {
+			return m, m.refreshNodes()
+		}
+		return m, nil
+
+	case metricsTickMsg:
+		return m, tea.Batch(getMetrics(m.clientset, m.state == StateSelectPods), metricsTick())
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case progressMsg:
+		m.progress.apply(msg)
+		return m, waitForOperation(m.progress.events)
+
+	case resultsMsg:
+		if m.state != StateProgress {
+			return m.showReport(msg)
+		}
+		// Keep the progress view up until the user asks for the summary
+		m.results = append(m.results, msg...)
+		for _, r := range msg {
+			if r.Failed() {
+				m.progress.failPending(r.Message)
+			}
+		}
+		m.progress.done = true
+		m.control.cancel()
+		if m.quitAfterOp {
+			m.quitting = true
+			return m, tea.Sequence(tea.ExitAltScreen, tea.Quit)
+		}
+		return m, nil
+
+	case impactMsg:
+		if m.state != StateImpact || msg.node != m.selectedNodeName {
+			return m, nil
+		}
+		m.analyzing = false
+		var b strings.Builder
+		if msg.err != nil {
+			fmt.Fprintf(&b, "Impact analysis failed: %v\n", msg.err)
+		} else {
+			_ = printImpact(&b, msg.report)
+		}
+		m.report.SetContent(b.String())
+		return m, nil
+
+	case nodeDetailMsg:
+		if m.state != StateNodeDetail || msg.node != m.selectedNodeName {
+			return m, nil
+		}
+		m.analyzing = false
+		var b strings.Builder
+		if msg.err != nil {
+			fmt.Fprintf(&b, "Failed to load node details: %v\n", msg.err)
+		} else {
+			_ = printNodeDetail(&b, msg.detail)
+		}
+		m.report.SetContent(b.String())
+		return m, nil
+
+	case blastMsg:
+		if (m.state != StateConfirm && m.state != StateConfirmPod) || msg.node != m.selectedNodeName {
+			return m, nil
+		}
+		items := append(m.list.Items(), blastRadiusItems(msg)...)
+		m.list.Title = blastRadiusTitle(m.list.Title, msg.workloads)
+		return m, m.list.SetItems(items)
+
+	case finishedPodsMsg:
+		if m.state != StateConfirmBulk || m.action != ActionClusterCleanup {
+			return m, nil
+		}
+		m.list = createList(clusterCleanupItems(msg), fmt.Sprintf("Confirm %s", m.action), m.width, m.height)
+		return m, nil
+
+	case preflightMsg:
+		if m.state != StatePreflight || msg.node != m.selectedNodeName {
+			return m, nil
+		}
+		m.list = createList(preflightItems(msg, m.drainSettings.DisableEviction), preflightTitle(msg.pdbs), m.width, m.height)
 		return m, nil
 
 	case podsMsg:
-		m.pods = msg
-		items := make([]list.Item, len(msg))
-		for i := range msg {
-			items[i] = msg[i]
+		if m.state != StateSelectPods || msg.node != m.selectedNodeName {
+			return m, nil
+		}
+		// Carry selections over, dropping pods that no longer exist
+		current := make(map[string]bool, len(msg.pods))
+		for i := range msg.pods {
+			key := msg.pods[i].namespace + "/" + msg.pods[i].name
+			current[key] = true
+			if _, ok := m.selectedPods[key]; ok {
+				msg.pods[i].selected = true
+				m.selectedPods[key] = msg.pods[i]
+			}
 		}
+		for key := range m.selectedPods {
+			if !current[key] {
+				delete(m.selectedPods, key)
+			}
+		}
+
+		m.pods = msg.pods
+		applyPodUsage(m.pods, m.podUsage)
+		sortPods(m.pods, m.podSort)
+		items := podItems(m.pods)
 
+		if _, ok := m.list.SelectedItem().(podInfo); ok {
+			return m, setItemsKeepCursor(&m.list, items)
+		}
 		m.list = createList(items, "Select Pods", m.width, m.height)
+		m.applyListView()
 		return m, nil
 	}
 
@@ -103,7 +351,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.selectedNode, _ = getNode(m.clientset, node.name)
 					m.state = StateConfirmToggle
 					action := MsgCordon
-					if !node.schedulable {
+					if !node.schedulable || node.taint != "" {
 						action = MsgUncordon
 					}
 					items := []list.Item{
@@ -113,19 +361,129 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.list = createList(items, fmt.Sprintf("Confirm %s Operation", action), m.width, m.height)
 					return m, nil
 				}
+			case KeySpace:
+				if node, ok := m.list.SelectedItem().(nodeInfo); ok && m.list.FilterState() != list.Filtering {
+					return m, m.toggleNodes(node)
+				}
+			case KeyS:
+				if _, ok := m.list.SelectedItem().(nodeInfo); ok && m.list.FilterState() != list.Filtering {
+					m.nodeSort = nextNodeSort(m.nodeSort)
+					m.syncPodCounts()
+					m.applyListView()
+					return m, m.refreshNodes()
+				}
+			case KeyT:
+				if m.list.FilterState() != list.Filtering {
+					m.tableView = !m.tableView
+					m.syncPodCounts()
+					m.applyListView()
+					return m, nil
+				}
+			case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+				if _, ok := m.list.SelectedItem().(nodeInfo); ok && m.tableView && m.list.FilterState() != list.Filtering {
+					m.nodeSort = sortByColumn(nodeColumns, m.nodeSort, int(keyMsg.String()[0]-'1'))
+					m.syncPodCounts()
+					m.applyListView()
+					return m, m.refreshNodes()
+				}
+			case KeyX:
+				if m.list.FilterState() != list.Filtering {
+					m.action = ActionClusterCleanup
+					m.results = nil
+					m.notice = ""
+					return m.confirmBulk()
+				}
+			case KeyA:
+				if m.list.FilterState() != list.Filtering {
+					var nodes []nodeInfo
+					for _, it := range m.list.VisibleItems() {
+						nodes = append(nodes, it.(nodeInfo))
+					}
+					return m, m.toggleNodes(nodes...)
+				}
 			case KeyEnter:
+				if len(m.selectedNodes) > 0 && m.list.FilterState() != list.Filtering {
+					m.results = nil
+					m.notice = ""
+					m.state = StateSelectBulkAction
+					m.list = createList(bulkActionItems(), fmt.Sprintf("Select Operation for %d Nodes", len(m.selectedNodes)), m.width, m.height)
+					return m, nil
+				}
 				if m.list.SelectedItem() != nil {
 					m.selectedNodeName = m.list.SelectedItem().(nodeInfo).name
 					m.selectedNode, _ = getNode(m.clientset, m.selectedNodeName)
+					m.results = nil
+					m.notice = ""
 					m.state = StateSelectAction
-					items := []list.Item{
-						item{title: ActionForceDrainNode, desc: DescDrainNode},
-						item{title: ActionForceDeleteNonDS, desc: DescForceDeleteNonDS},
-						item{title: ActionForceDeleteSelected, desc: DescForceDeleteSelected},
-						item{title: ActionBack, desc: DescBack},
+					m.list = createList(actionItems(), "Select Operation", m.width, m.height)
+				}
+			}
+		}
+
+	case StateResume:
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case KeyEnter:
+				selected, ok := m.list.SelectedItem().(item)
+				if !ok {
+					return m, nil
+				}
+				switch selected.Title() {
+				case ActionResume:
+					return m.resume()
+				case ActionDiscard:
+					m.state = StateSelectNode
+					if m.dryRun != cmdutil.DryRunNone {
+						m.notice = "Dry run: the unfinished operations were kept"
+						return m, getNodes(m.clientset)
 					}
-					m.list = createList(items, "Select Operation", m.width, m.height)
+					return m, discardOperations(m.clientset, m.unfinished)
 				}
+				m.state = StateSelectNode
+				return m, getNodes(m.clientset)
+			case KeyEsc:
+				m.state = StateSelectNode
+				return m, getNodes(m.clientset)
+			}
+		}
+
+	case StateSelectBulkAction:
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case KeyEnter:
+				if selected, ok := m.list.SelectedItem().(item); ok {
+					m.action = selected.Title()
+					switch m.action {
+					case ActionBack:
+						m.state = StateSelectNode
+						return m, getNodes(m.clientset)
+					case ActionBulkDrain, ActionBulkRollout:
+						m.state = StateDrainOptions
+						m.list = createList(m.optionItems(), m.optionsTitle(), m.width, m.height)
+						return m, nil
+					}
+					return m.confirmBulk()
+				}
+			case KeyEsc:
+				m.state = StateSelectNode
+				return m, getNodes(m.clientset)
+			}
+		}
+
+	case StateConfirmBulk:
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case KeyEnter:
+				selected, ok := m.list.SelectedItem().(item)
+				if !ok {
+					return m, nil
+				}
+				if selected.Title() == ConfirmYes {
+					return m.runBulk()
+				}
+				return m.backToActions()
+			case KeyEsc:
+				return m.backToActions()
 			}
 		}
 
@@ -140,15 +498,93 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						return m, getNodes(m.clientset)
 					}
 
-					// First confirm cordon operation
-					m.state = StateConfirmCordon
-					items := []list.Item{
-						item{title: ConfirmYes, desc: fmt.Sprintf("Confirm cordon node %s before %s", m.selectedNodeName, m.action)},
-						item{title: ConfirmNo, desc: DescCancelBack},
+					if m.action == ActionAnalyzeImpact {
+						h, v := lipgloss.NewStyle().Margin(1, 2).GetFrameSize()
+						m.state = StateImpact
+						m.analyzing = true
+						m.report = viewport.New(m.width-h, m.height-v-reportChrome)
+						return m, analyzeImpact(m.clientset, m.selectedNodeName)
+					}
+
+					// Cleanup leaves the node schedulable, so there is nothing to cordon
+					if m.action == ActionCleanupFinished {
+						return m.confirmCleanup()
+					}
+
+					// Let the user review drain or delete options before cordoning
+					if m.action == ActionForceDrainNode || isDeleteAction(m.action) {
+						m.state = StateDrainOptions
+						m.list = createList(m.optionItems(), m.optionsTitle(), m.width, m.height)
+						return m, nil
+					}
+
+					return m.confirmCordon()
+				}
+			}
+		}
+
+	case StateDrainOptions:
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case KeyEnter:
+				switch selected := m.list.SelectedItem().(type) {
+				case drainOptionItem:
+					if selected.key == optionPodSelector {
+						m.state = StateEditOption
+						m.editingOption = selected.key
+						m.input.SetValue(m.drainSettings.PodSelector)
+						m.input.CursorEnd()
+						return m, m.input.Focus()
+					}
+					if selected.key == optionMaxUnavailable {
+						m.rolloutSettings.MaxUnavailable = nextChoice(maxUnavailableChoices, m.rolloutSettings.MaxUnavailable)
+					} else if isDeleteAction(m.action) {
+						m.deleteSettings.cycle(selected.key)
+					} else {
+						m.drainSettings.cycle(selected.key)
 					}
-					m.list = createList(items, "Confirm Cordon Operation", m.width, m.height)
+					index := m.list.Index()
+					m.list.SetItems(m.optionItems())
+					m.list.Select(index)
 					return m, nil
+				case item:
+					if selected.Title() == ActionContinue && isBulkAction(m.action) {
+						return m.confirmBulk()
+					}
+					if selected.Title() == ActionContinue && isDeleteAction(m.action) {
+						return m.confirmCordon()
+					}
+					if selected.Title() == ActionContinue {
+						// Check PodDisruptionBudgets before anything is cordoned
+						m.state = StatePreflight
+						m.list = createList([]list.Item{}, "Disruption Budgets", m.width, m.height)
+						return m, checkDisruptionBudgets(m.clientset, m.selectedNodeName, m.drainSettings.PodSelector)
+					}
+					return m.backToActions()
+				}
+			case KeyEsc:
+				return m.backToActions()
+			}
+		}
+
+	case StatePreflight:
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case KeyEnter:
+				if selected, ok := m.list.SelectedItem().(item); ok {
+					switch selected.Title() {
+					case ActionContinue:
+						return m.confirmCordon()
+					case ActionBack:
+						m.state = StateDrainOptions
+						m.list = createList(m.optionItems(), m.optionsTitle(), m.width, m.height)
+						return m, nil
+					}
 				}
+			case KeyEsc:
+				m.state = StateDrainOptions
+				m.list = createList(m.optionItems(), m.optionsTitle(), m.width, m.height)
+				return m, nil
 			}
 		}
 
@@ -157,109 +593,69 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if keyMsg.String() == "enter" {
 				if m.list.SelectedItem() != nil {
 					confirm := m.list.SelectedItem().(item).Title()
-					if confirm == ConfirmYes {
-						drainer := newDrainer(m.clientset)
-						if err := drain.RunCordonOrUncordon(drainer, m.selectedNode, true); err != nil {
-							m.err = err
-							return m, nil
-						}
-						fmt.Printf("Successfully cordoned node %s\n", m.selectedNode.Name)
-
-						// After cordon, proceed to confirm the main operation
-						if m.action == ActionForceDeleteSelected {
-							m.state = StateSelectPods
-							return m, getPods(m.clientset, m.selectedNodeName)
+					if confirm == ConfirmYes || confirm == ActionTaint || confirm == ActionCordonAndTaint {
+						m.maintenanceMode = ModeCordon
+						if confirm == ActionTaint {
+							m.maintenanceMode = ModeTaint
+						} else if confirm == ActionCordonAndTaint {
+							m.maintenanceMode = ModeCordonAndTaint
 						}
-
-						m.state = StateConfirm
-						items := []list.Item{
-							item{title: ConfirmYes, desc: fmt.Sprintf("Confirm %s on node %s", m.action, m.selectedNodeName)},
-							item{title: ConfirmNo, desc: DescCancelBack},
+						if m.maintenanceMode != ModeTaint && !m.selectedNode.Spec.Unschedulable {
+							return m.promptCordonReason(StateConfirmCordon)
 						}
-						m.list = createList(items, "Confirm Operation", m.width, m.height)
+						return m.cordonAndConfirm()
 					} else {
 						// Go back to action selection
 						m.state = StateSelectAction
-						items := []list.Item{
-							item{title: ActionForceDrainNode, desc: DescDrainNode},
-							item{title: ActionForceDeleteNonDS, desc: DescForceDeleteNonDS},
-							item{title: ActionForceDeleteSelected, desc: DescForceDeleteSelected},
-							item{title: ActionBack, desc: DescBack},
-						}
-						m.list = createList(items, "Select Operation", m.width, m.height)
+						m.list = createList(actionItems(), "Select Operation", m.width, m.height)
 					}
 					return m, nil
 				}
 			}
 		} else if keyMsg.String() == KeyEsc {
 			m.state = StateSelectAction
-			items := []list.Item{
-				item{title: ActionForceDrainNode, desc: DescDrainNode},
-				item{title: ActionForceDeleteNonDS, desc: DescForceDeleteNonDS},
-				item{title: ActionForceDeleteSelected, desc: DescForceDeleteSelected},
-				item{title: ActionBack, desc: DescBack},
-			}
-			m.list = createList(items, "Select Operation", m.width, m.height)
+			m.list = createList(actionItems(), "Select Operation", m.width, m.height)
 			return m, nil
 		}
 
 	case StateConfirm:
 		if keyMsg, ok := msg.(tea.KeyMsg); ok {
 			if keyMsg.String() == "enter" {
-				if m.list.SelectedItem() != nil {
-					confirm := m.list.SelectedItem().(item).Title()
+				// Workload entries below Yes/No are informational
+				if selected, ok := m.list.SelectedItem().(item); ok && (selected.Title() == ConfirmYes || selected.Title() == ConfirmNo) {
+					confirm := selected.Title()
 					if confirm == ConfirmYes {
-						drainer := newDrainer(m.clientset)
-						if err := drain.RunCordonOrUncordon(drainer, m.selectedNode, true); err != nil {
-							m.err = err
-							return m, nil
+						if m.action != ActionCleanupFinished {
+							if err := m.cordonSelectedNode(); err != nil {
+								m.err = err
+								return m, nil
+							}
 						}
-						fmt.Printf("Successfully cordoned node %s\n", m.selectedNode.Name)
 
+						opts := append(m.drainSettings.Options(), WithDryRunStrategy(m.dryRun))
+						nodeName := m.selectedNodeName
+						clientset := m.clientset
+						dryRun := m.dryRun
+						settings := m.deleteSettings
 						switch m.action {
 						case ActionForceDrainNode:
-							return m, func() tea.Msg {
-								if err := drain.RunNodeDrain(drainer, m.selectedNode.Name); err != nil {
-									return fmt.Errorf("failed to drain node %s: %v", m.selectedNodeName, err)
-								}
-								fmt.Printf("Successfully drained node %s\n", m.selectedNodeName)
-								m.quitting = true
-								return nil
-							}
+							return m.runOperation(fmt.Sprintf("Draining node %s", nodeName), func(ctrl *control, progress progressFunc, log io.Writer) []NodeResult {
+								drainer := newDrainer(clientset, append(opts, WithOutput(log, log), WithContext(ctrl.ctx))...)
+								return []NodeResult{drainNode(drainer, nodeName, progress, ctrl)}
+							})
 						case ActionForceDeleteNonDS:
-							return m, func() tea.Msg {
-								pods, err := m.clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
-									FieldSelector: fmt.Sprintf("spec.nodeName=%s", m.selectedNodeName),
-								})
-								if err != nil {
-									return fmt.Errorf("failed to get pods on node %s: %v", m.selectedNodeName, err)
-								}
-
-								for _, pod := range pods.Items {
-									if !isDaemonSetPod(pod) {
-										err = m.clientset.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{
-											GracePeriodSeconds: new(int64),
-										})
-										if err != nil {
-											fmt.Printf("Failed to delete pod %s/%s: %v\n", pod.Namespace, pod.Name, err)
-										} else {
-											fmt.Printf("Successfully deleted pod %s/%s\n", pod.Namespace, pod.Name)
-										}
-									}
-								}
-								return nil
-							}
+							return m.runOperation(fmt.Sprintf("Deleting non-DaemonSet pods on node %s", nodeName), func(ctrl *control, progress progressFunc, _ io.Writer) []NodeResult {
+								return []NodeResult{deleteNonDaemonSetPods(ctrl.ctx, clientset, nodeName, settings, dryRun, progress, ctrl)}
+							})
+						case ActionCleanupFinished:
+							return m.runOperation(fmt.Sprintf("Cleaning up finished pods on node %s", nodeName), func(ctrl *control, progress progressFunc, _ io.Writer) []NodeResult {
+								return []NodeResult{cleanupFinishedPods(ctrl.ctx, clientset, nodeName, dryRun, progress, ctrl)}
+							})
 						}
 					} else {
 						// Go back to action selection
 						m.state = StateSelectAction
-						items := []list.Item{
-							item{title: ActionForceDrainNode, desc: DescDrainNode},
-							item{title: ActionForceDeleteNonDS, desc: DescForceDeleteNonDS},
-							item{title: ActionForceDeleteSelected, desc: DescForceDeleteSelected},
-							item{title: ActionBack, desc: DescBack},
-						}
-						m.list = createList(items, "Select Operation", m.width, m.height)
+						m.list = createList(actionItems(), "Select Operation", m.width, m.height)
 					}
 				}
 			}
@@ -268,42 +664,57 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case StateSelectPods:
 		if keyMsg, ok := msg.(tea.KeyMsg); ok {
 			switch keyMsg.String() {
-			case KeySpace:
-				if m.list.SelectedItem() != nil {
-					pod := m.list.SelectedItem().(podInfo)
-					key := pod.namespace + "/" + pod.name
-					// Update pod selection status in the pods list
-					for i := range m.pods {
-						if m.pods[i].name == pod.name && m.pods[i].namespace == pod.namespace {
-							m.pods[i].selected = !m.pods[i].selected
-							break
-						}
-					}
-					// Update the list items to reflect the selection
-					currentIndex := m.list.Index()
-					items := make([]list.Item, len(m.pods))
-					for i := range m.pods {
-						items[i] = m.pods[i]
-					}
-					m.list.SetItems(items)
-					m.list.Select(currentIndex)
-
-					if _, exists := m.selectedPods[key]; exists {
-						delete(m.selectedPods, key)
-					} else {
-						m.selectedPods[key] = pod
-					}
+			case KeyT:
+				if m.list.FilterState() != list.Filtering {
+					m.tableView = !m.tableView
+					m.applyListView()
 					return m, nil
 				}
+			case "1", "2", "3", "4", "5", "6", "7":
+				if _, ok := m.list.SelectedItem().(podInfo); ok && m.tableView && m.list.FilterState() != list.Filtering {
+					m.podSort = sortByColumn(podColumns, m.podSort, int(keyMsg.String()[0]-'1'))
+					m.applyListView()
+					return m, m.refreshPods()
+				}
+			case KeySpace:
+				if pod, ok := m.list.SelectedItem().(podInfo); ok {
+					return m, m.togglePods(func(p podInfo) bool { return p.key() == pod.key() })
+				}
+			case KeyA:
+				return m, m.togglePods(func(podInfo) bool { return true })
+			case KeyN:
+				return m, m.selectPods(func(podInfo) bool { return false })
+			case KeyI:
+				return m, m.selectPods(func(p podInfo) bool { return !p.selected })
+			case KeyV:
+				shown := make(map[string]bool)
+				for _, it := range m.list.VisibleItems() {
+					shown[it.(podInfo).key()] = true
+				}
+				return m, m.togglePods(func(p podInfo) bool { return shown[p.key()] })
+			case KeySameNamespace:
+				if pod, ok := m.list.SelectedItem().(podInfo); ok {
+					return m, m.togglePods(func(p podInfo) bool { return p.namespace == pod.namespace })
+				}
+			case KeySameOwner:
+				if pod, ok := m.list.SelectedItem().(podInfo); ok {
+					return m, m.togglePods(func(p podInfo) bool { return sameOwner(p, pod) })
+				}
+			case KeySamePhase:
+				if pod, ok := m.list.SelectedItem().(podInfo); ok {
+					return m, m.togglePods(func(p podInfo) bool { return p.phase == pod.phase })
+				}
 			case KeyEnter:
 				if len(m.selectedPods) > 0 {
 					// Show confirmation for pod deletion
+					m.watcher.stopPods()
 					m.state = StateConfirmPod
 					items := []list.Item{
 						item{title: ConfirmYes, desc: fmt.Sprintf("Confirm delete %d selected pods", len(m.selectedPods))},
 						item{title: ConfirmNo, desc: DescCancelBack},
 					}
 					m.list = createList(items, "Confirm Pod Deletion", m.width, m.height)
+					return m, checkBlastRadius(m.clientset, m.selectedNodeName, selectedPodsFilter(m.selectedPods))
 				}
 			}
 		}
@@ -311,35 +722,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case StateConfirmPod:
 		if keyMsg, ok := msg.(tea.KeyMsg); ok {
 			if keyMsg.String() == "enter" {
-				if m.list.SelectedItem() != nil {
-					confirm := m.list.SelectedItem().(item).Title()
+				// Workload entries below Yes/No are informational
+				if selected, ok := m.list.SelectedItem().(item); ok && (selected.Title() == ConfirmYes || selected.Title() == ConfirmNo) {
+					confirm := selected.Title()
 					if confirm == ConfirmYes {
-						drainer := newDrainer(m.clientset)
-						if err := drain.RunCordonOrUncordon(drainer, m.selectedNode, true); err != nil {
+						if err := m.cordonSelectedNode(); err != nil {
 							m.err = err
 							return m, nil
 						}
-						fmt.Printf("Successfully cordoned node %s\n", m.selectedNode.Name)
 						// Delete all selected pods
-						var cmds []tea.Cmd
+						pods := make([]podInfo, 0, len(m.selectedPods))
 						for _, pod := range m.selectedPods {
-							cmds = append(cmds, deletePod(m.clientset, pod.namespace, pod.name))
+							pods = append(pods, pod)
 						}
-						cmds = append(cmds, func() tea.Msg {
-							m.quitting = true
-							return nil
+						nodeName := m.selectedNodeName
+						clientset := m.clientset
+						dryRun := m.dryRun
+						settings := m.deleteSettings
+						return m.runOperation(fmt.Sprintf("Deleting selected pods on node %s", nodeName), func(ctrl *control, progress progressFunc, _ io.Writer) []NodeResult {
+							return []NodeResult{deleteSelectedPods(ctrl.ctx, clientset, nodeName, pods, settings, dryRun, progress, ctrl)}
 						})
-						return m, tea.Sequence(cmds...)
 					} else {
 						// Go back to action selection
 						m.state = StateSelectAction
-						items := []list.Item{
-							item{title: ActionForceDrainNode, desc: DescDrainNode},
-							item{title: ActionForceDeleteNonDS, desc: DescForceDeleteNonDS},
-							item{title: ActionForceDeleteSelected, desc: DescForceDeleteSelected},
-							item{title: ActionBack, desc: DescBack},
-						}
-						m.list = createList(items, "Select Operation", m.width, m.height)
+						m.list = createList(actionItems(), "Select Operation", m.width, m.height)
 					}
 				}
 			}
@@ -351,18 +757,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.list.SelectedItem() != nil {
 					confirm := m.list.SelectedItem().(item).Title()
 					if confirm == ConfirmYes {
-						node := m.selectedNode
-						drainer := newDrainer(m.clientset)
-						// Toggle cordon state
-						if err := drain.RunCordonOrUncordon(drainer, node, !node.Spec.Unschedulable); err != nil {
-							m.err = err
-							return m, nil
-						}
-						action := MsgCordon
-						if node.Spec.Unschedulable {
-							action = MsgUncordon
+						if !inMaintenance(m.selectedNode) {
+							return m.promptCordonReason(StateConfirmToggle)
 						}
-						fmt.Printf("Successfully %sed node %s\n", action, node.Name)
+						return m.toggleCordon()
 					}
 					// Return to node selection with refreshed list
 					m.state = StateSelectNode
@@ -378,13 +776,624 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// toggleCordon cordons the selected node, or ends its maintenance by
+// uncordoning it and removing the maintenance taint, and returns to the node
+// list
+func (m model) toggleCordon() (tea.Model, tea.Cmd) {
+	node := m.selectedNode
+	drainer := newDrainer(m.clientset, WithContext(m.ctx), WithDryRunStrategy(m.dryRun))
+	result := cordonNode(drainer, node, !inMaintenance(node), m.cordonNote)
+	m.cordonNote = nil
+	if result.Failed() {
+		m.err = errors.New(result.Message)
+		return m, nil
+	}
+	if m.dryRun != cmdutil.DryRunNone {
+		return m.showReport([]NodeResult{result})
+	}
+	m.notice = fmt.Sprintf("Successfully %sed node %s", result.Action, node.Name)
+	m.state = StateSelectNode
+	return m, getNodes(m.clientset)
+}
+
+// cordonAndConfirm cordons the selected node and asks to confirm m.action
+func (m model) cordonAndConfirm() (tea.Model, tea.Cmd) {
+	if err := m.cordonSelectedNode(); err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	// After cordon, proceed to confirm the main operation
+	if m.action == ActionForceDeleteSelected {
+		m.state = StateSelectPods
+		m.watcher.watchPods(m.selectedNodeName)
+		return m, tea.Batch(getPods(m.clientset, m.selectedNodeName), getMetrics(m.clientset, true))
+	}
+
+	m.state = StateConfirm
+	items := []list.Item{
+		item{title: ConfirmYes, desc: fmt.Sprintf("Confirm %s on node %s", m.action, m.selectedNodeName)},
+		item{title: ConfirmNo, desc: DescCancelBack},
+	}
+	m.list = createList(items, "Confirm Operation", m.width, m.height)
+	return m, checkBlastRadius(m.clientset, m.selectedNodeName, drainedPods(m.blastRadiusSelector()))
+}
+
+// promptCordonReason asks why the selected node is cordoned before the
+// confirmation in from goes ahead
+func (m model) promptCordonReason(from State) (tea.Model, tea.Cmd) {
+	m.state = StateCordonReason
+	m.reasonFor = from
+	m.cordonNote = &CordonNote{}
+	m.input.SetValue("")
+	return m, m.input.Focus()
+}
+
+// updateCordonReason reads the reason and then the optional ticket ID. The
+// reason is required; esc cancels the cordon.
+func (m model) updateCordonReason(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case KeyEnter:
+		value := strings.TrimSpace(m.input.Value())
+		if m.cordonNote.Reason == "" {
+			if value != "" {
+				m.cordonNote.Reason = value
+				m.input.SetValue("")
+			}
+			return m, nil
+		}
+		m.cordonNote.Ticket = value
+		m.cordonNote.By = m.operator.name()
+		m.cordonNote.At = time.Now()
+		m.input.Blur()
+		if m.reasonFor == StateConfirmToggle {
+			return m.toggleCordon()
+		}
+		return m.cordonAndConfirm()
+	case KeyEsc:
+		m.input.Blur()
+		m.cordonNote = nil
+		if m.reasonFor == StateConfirmToggle {
+			m.state = StateSelectNode
+			return m, getNodes(m.clientset)
+		}
+		m.state = StateSelectAction
+		m.list = createList(actionItems(), "Select Operation", m.width, m.height)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// cordonSelectedNode cordons or taints the selected node ahead of a
+// destructive action, as the maintenance mode says, and keeps the results for
+// the final report
+func (m *model) cordonSelectedNode() error {
+	drainer := newDrainer(m.clientset, WithContext(m.ctx), WithDryRunStrategy(m.dryRun))
+	var results []NodeResult
+	if m.maintenanceMode != ModeTaint {
+		results = append(results, cordonNode(drainer, m.selectedNode, true, m.cordonNote))
+	}
+	if m.maintenanceMode == ModeTaint || m.maintenanceMode == ModeCordonAndTaint {
+		results = append(results, taintNode(drainer, m.selectedNode, m.maintenanceTaint))
+	}
+	for _, result := range results {
+		if result.Failed() {
+			return errors.New(result.Message)
+		}
+		if result.Status == ResultSucceeded {
+			m.results = append(m.results, result)
+		}
+	}
+	return nil
+}
+
+// runOperation starts a long-running action and shows its progress. The
+// action gets a control so the progress view can pause or cancel it.
+func (m model) runOperation(title string, fn func(ctrl *control, progress progressFunc, log io.Writer) []NodeResult) (tea.Model, tea.Cmd) {
+	ctrl := newControl(m.ctx)
+	events := startOperation(func(progress progressFunc, log io.Writer) []NodeResult {
+		return fn(ctrl, progress, log)
+	})
+	m.control = ctrl
+	m.state = StateProgress
+	m.progress = newProgressView(title, events, m.width, m.height)
+	return m, tea.Batch(waitForOperation(events), m.spinner.Tick)
+}
+
+// updateProgress scrolls the pod rows and the log of a running action and
+// pauses or cancels it; once it has finished, enter opens the summary
+func (m model) updateProgress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case KeyP:
+		if !m.progress.done && !m.progress.canceling {
+			m.progress.paused = m.control.togglePause()
+		}
+	case KeyR:
+		if !m.progress.done && m.gate != nil {
+			m.gate.releaseNext()
+		}
+	case KeyX, KeyCtrlC:
+		if !m.progress.done {
+			m.control.cancel()
+			m.progress.paused = false
+			m.progress.canceling = true
+		}
+	case "up", "k":
+		m.progress.podView.LineUp(1)
+	case "down", "j":
+		m.progress.podView.LineDown(1)
+	case "pgup", "b":
+		m.progress.logView.HalfViewUp()
+	case "pgdown", "f":
+		m.progress.logView.HalfViewDown()
+	case KeyEnter:
+		if m.progress.done {
+			return m.showReport(nil)
+		}
+	}
+	return m, nil
+}
+
+// showReport switches to the report screen listing results of the finished
+// action together with the cordon results gathered before it
+func (m model) showReport(results []NodeResult) (tea.Model, tea.Cmd) {
+	m.results = append(m.results, results...)
+	m.state = StateReport
+	h, v := lipgloss.NewStyle().Margin(1, 2).GetFrameSize()
+	m.report = viewport.New(m.width-h, m.height-v-reportChrome)
+	m.report.SetContent(formatReport(m.results, m.dryRun))
+	return m, nil
+}
+
+// updateReport scrolls the report, esc goes back to a refreshed node list
+func (m model) updateReport(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == KeyEsc {
+		m.results = nil
+		m.selectedPods = make(map[string]podInfo)
+		m.selectedNodes = make(map[string]nodeInfo)
+		m.gate = nil
+		m.state = StateSelectNode
+		return m, getNodes(m.clientset)
+	}
+
+	var cmd tea.Cmd
+	m.report, cmd = m.report.Update(msg)
+	return m, cmd
+}
+
+// updateImpact scrolls the impact analysis, esc goes back to the actions
+func (m model) updateImpact(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == KeyEsc {
+		m.analyzing = false
+		m.state = StateSelectAction
+		m.list = createList(actionItems(), "Select Operation", m.width, m.height)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.report, cmd = m.report.Update(msg)
+	return m, cmd
+}
+
+// showNodeDetail opens the detail view of node
+func (m model) showNodeDetail(node nodeInfo) (tea.Model, tea.Cmd) {
+	h, v := lipgloss.NewStyle().Margin(1, 2).GetFrameSize()
+	m.selectedNodeName = node.name
+	m.state = StateNodeDetail
+	m.analyzing = true
+	m.report = viewport.New(m.width-h, m.height-v-reportChrome)
+	return m, loadNodeDetail(m.clientset, node.name)
+}
+
+// updateNodeDetail scrolls the node detail, esc goes back to the node list
+func (m model) updateNodeDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == KeyEsc {
+		m.analyzing = false
+		m.state = StateSelectNode
+		return m, getNodes(m.clientset)
+	}
+
+	var cmd tea.Cmd
+	m.report, cmd = m.report.Update(msg)
+	return m, cmd
+}
+
+// refreshNodes applies the latest usage and order to the shown nodes
+func (m *model) refreshNodes() tea.Cmd {
+	nodes := make([]nodeInfo, 0, len(m.list.Items()))
+	for _, it := range m.list.Items() {
+		nodes = append(nodes, it.(nodeInfo))
+	}
+	applyNodeUsage(nodes, m.nodeUsage)
+	applyPodCounts(nodes, m.podCounts)
+	sortNodes(nodes, m.nodeSort)
+	m.nodeIndex.set(nodes)
+	items := make([]list.Item, len(nodes))
+	for i := range nodes {
+		items[i] = nodes[i]
+	}
+	return setItemsKeepCursor(&m.list, items)
+}
+
+// refreshPods applies the latest usage and order to the shown pods
+func (m *model) refreshPods() tea.Cmd {
+	applyPodUsage(m.pods, m.podUsage)
+	sortPods(m.pods, m.podSort)
+	return setItemsKeepCursor(&m.list, podItems(m.pods))
+}
+
+// applyListView shows the node or pod list as items or as a table
+func (m *model) applyListView() {
+	var columns []tableColumn
+	switch m.state {
+	case StateSelectNode:
+		m.list.Title = m.nodeListTitle()
+		columns = nodeColumns
+	case StateSelectPods:
+		columns = podColumns
+	default:
+		return
+	}
+	setTableView(&m.list, m.tableView, columns)
+	h, v := lipgloss.NewStyle().Margin(1, 2).GetFrameSize()
+	if m.tableView {
+		v += tableChrome
+	}
+	m.list.SetSize(m.width-h, m.height-v)
+}
+
+// renderTable renders the node or pod table with its title and column titles
+func (m model) renderTable() string {
+	columns, order := nodeColumns, m.nodeSort
+	if m.state == StateSelectPods {
+		columns, order = podColumns, m.podSort
+	}
+	return tableTitleView(m.list, columns, order) + "\n" + m.list.View()
+}
+
+// sortHelp names the column sort keys while the table view is shown
+func (m model) sortHelp() string {
+	if !m.tableView {
+		return ""
+	}
+	return " • 1-9: Sort by column"
+}
+
+// podItems converts pods into list items
+func podItems(pods []podInfo) []list.Item {
+	items := make([]list.Item, len(pods))
+	for i := range pods {
+		items[i] = pods[i]
+	}
+	return items
+}
+
+// nodeListTitle names the node list and its order
+func (m model) nodeListTitle() string {
+	if m.nodeSort == defaultSort(SortByName) {
+		return "Select Node"
+	}
+	direction := "ascending"
+	if m.nodeSort.desc {
+		direction = "descending"
+	}
+	return fmt.Sprintf("Select Node (by %s, %s)", m.nodeSort.column, direction)
+}
+
+// needsPodCounts reports whether the node list shows or sorts by pod counts
+func (m model) needsPodCounts() bool {
+	return m.tableView || m.nodeSort.column == SortByPods
+}
+
+// syncPodCounts watches the pods of all nodes only while the node list needs
+// their counts
+func (m *model) syncPodCounts() {
+	if m.needsPodCounts() {
+		m.watcher.watchPodCounts()
+		return
+	}
+	m.watcher.stopPodCounts()
+	m.podCounts = nil
+}
+
+// toggleNodes flips the selection of the given nodes. When every one of them
+// is already selected they are all deselected, otherwise all get selected.
+func (m *model) toggleNodes(nodes ...nodeInfo) tea.Cmd {
+	selectAll := false
+	for _, node := range nodes {
+		if _, ok := m.selectedNodes[node.name]; !ok {
+			selectAll = true
+			break
+		}
+	}
+	for _, node := range nodes {
+		if selectAll {
+			node.selected = true
+			m.selectedNodes[node.name] = node
+		} else {
+			delete(m.selectedNodes, node.name)
+		}
+	}
+
+	// Update the list items to reflect the selection
+	items := m.list.Items()
+	for i, it := range items {
+		node := it.(nodeInfo)
+		_, node.selected = m.selectedNodes[node.name]
+		items[i] = node
+	}
+	return setItemsKeepCursor(&m.list, items)
+}
+
+// togglePods flips the selection of the pods in a group, the way toggleNodes
+// does: when every one of them is already selected they are all deselected,
+// otherwise all get selected. Other pods keep their selection.
+func (m *model) togglePods(inGroup func(p podInfo) bool) tea.Cmd {
+	selectAll := false
+	for _, p := range m.pods {
+		if inGroup(p) && !p.selected {
+			selectAll = true
+			break
+		}
+	}
+	return m.selectPods(func(p podInfo) bool {
+		if inGroup(p) {
+			return selectAll
+		}
+		return p.selected
+	})
+}
+
+// selectPods sets the selection of every pod, keeping m.selectedPods and the
+// list markers in step
+func (m *model) selectPods(selected func(p podInfo) bool) tea.Cmd {
+	for i := range m.pods {
+		m.pods[i].selected = selected(m.pods[i])
+	}
+	m.selectedPods = make(map[string]podInfo)
+	for _, p := range m.pods {
+		if p.selected {
+			m.selectedPods[p.key()] = p
+		}
+	}
+	return setItemsKeepCursor(&m.list, podItems(m.pods))
+}
+
+// sameOwner reports whether two pods belong to the same controller, like one
+// ReplicaSet or StatefulSet. A pod without an owner is only its own group.
+func sameOwner(a, b podInfo) bool {
+	if a.owner == "" || b.owner == "" {
+		return a.key() == b.key()
+	}
+	return a.namespace == b.namespace && a.ownerKind == b.ownerKind && a.owner == b.owner
+}
+
+// backToActions returns to the operation menu the current action came from
+func (m model) backToActions() (tea.Model, tea.Cmd) {
+	if m.action == ActionClusterCleanup {
+		m.state = StateSelectNode
+		return m, getNodes(m.clientset)
+	}
+	if isBulkAction(m.action) {
+		m.state = StateSelectBulkAction
+		m.list = createList(bulkActionItems(), fmt.Sprintf("Select Operation for %d Nodes", len(m.selectedNodes)), m.width, m.height)
+		return m, nil
+	}
+	m.state = StateSelectAction
+	m.list = createList(actionItems(), "Select Operation", m.width, m.height)
+	return m, nil
+}
+
+// confirmBulk asks once for the bulk action on all selected nodes
+func (m model) confirmBulk() (tea.Model, tea.Cmd) {
+	m.state = StateConfirmBulk
+	if m.action == ActionClusterCleanup {
+		// The pods are counted before the cleanup can be confirmed
+		m.list = createList([]list.Item{}, "Counting Finished Pods", m.width, m.height)
+		return m, checkFinishedPods(m.clientset)
+	}
+	items := []list.Item{
+		item{title: ConfirmYes, desc: fmt.Sprintf("Confirm %s on %d nodes", m.action, len(m.selectedNodes))},
+		item{title: ConfirmNo, desc: DescCancelBack},
+	}
+	for _, name := range m.selectedNodeNames() {
+		items = append(items, m.selectedNodes[name])
+	}
+	m.list = createList(items, fmt.Sprintf("Confirm %s", m.action), m.width, m.height)
+	return m, nil
+}
+
+// runBulk runs the bulk action across the selected nodes
+func (m model) runBulk() (tea.Model, tea.Cmd) {
+	names := m.selectedNodeNames()
+	clientset := m.clientset
+	opts := append(m.drainSettings.Options(), WithDryRunStrategy(m.dryRun))
+
+	if m.action == ActionBulkRollout {
+		settings := m.rolloutSettings
+		gate := newKeyGate()
+		m.gate = gate
+		return m.runOperation(fmt.Sprintf("Rolling maintenance of %d nodes", len(names)), func(ctrl *control, progress progressFunc, log io.Writer) []NodeResult {
+			nodes, err := resolveNodes(ctrl.ctx, clientset, names, "")
+			if err != nil {
+				return failedResults(names, MsgRollout, err)
+			}
+			rolloutOpts := append(opts, WithOutput(log, log), WithContext(ctrl.ctx))
+			return rolloutNodes(ctrl.ctx, clientset, nodes, rolloutOpts, settings, gate.wait, progress, ctrl)
+		})
+	}
+
+	if m.action == ActionBulkCleanup || m.action == ActionClusterCleanup {
+		dryRun := m.dryRun
+		if m.action == ActionClusterCleanup {
+			return m.runOperation("Cleaning up finished pods on all nodes", func(ctrl *control, progress progressFunc, _ io.Writer) []NodeResult {
+				results, err := cleanupCluster(ctrl.ctx, clientset, dryRun, progress, ctrl)
+				if err != nil {
+					return failedResults([]string{"all nodes"}, ActionCleanupFinished, err)
+				}
+				return results
+			})
+		}
+		return m.runOperation(fmt.Sprintf("Cleaning up finished pods on %d nodes", len(names)), func(ctrl *control, progress progressFunc, _ io.Writer) []NodeResult {
+			return cleanupNodes(ctrl.ctx, clientset, names, dryRun, progress, ctrl)
+		})
+	}
+
+	if m.action == ActionBulkDrain {
+		return m.runOperation(fmt.Sprintf("Draining %d nodes", len(names)), func(ctrl *control, progress progressFunc, log io.Writer) []NodeResult {
+			drainer := newDrainer(clientset, append(opts, WithOutput(log, log), WithContext(ctrl.ctx))...)
+			nodes, err := resolveNodes(ctrl.ctx, clientset, names, "")
+			if err != nil {
+				return failedResults(names, MsgDrain, err)
+			}
+			return drainNodes(drainer, nodes, progress, ctrl)
+		})
+	}
+
+	desired := m.action == ActionBulkCordon
+	dryRun := m.dryRun
+	ctx := m.ctx
+	return m, func() tea.Msg {
+		action := MsgUncordon
+		if desired {
+			action = MsgCordon
+		}
+		nodes, err := resolveNodes(ctx, clientset, names, "")
+		if err != nil {
+			return resultsMsg(failedResults(names, action, err))
+		}
+		drainer := newDrainer(clientset, WithContext(ctx), WithDryRunStrategy(dryRun))
+		return resultsMsg(cordonNodes(drainer, nodes, desired))
+	}
+}
+
+// resume runs the unfinished operations again with their saved options
+func (m model) resume() (tea.Model, tea.Cmd) {
+	clientset := m.clientset
+	ops := m.unfinished
+	dryRun := m.dryRun
+	return m.runOperation(fmt.Sprintf("Resuming %d operations", len(ops)), func(ctrl *control, progress progressFunc, log io.Writer) []NodeResult {
+		opts := []DrainerOption{WithContext(ctrl.ctx), WithDryRunStrategy(dryRun), WithOutput(log, log)}
+		return resumeOperations(ctrl.ctx, clientset, ops, opts, progress, ctrl)
+	})
+}
+
+// optionItems returns the options shown before a drain, rollout or deletion
+func (m model) optionItems() []list.Item {
+	if m.action == ActionBulkRollout {
+		return rolloutOptionItems(m.drainSettings, m.rolloutSettings)
+	}
+	if isDeleteAction(m.action) {
+		return deleteOptionItems(m.deleteSettings)
+	}
+	return drainOptionItems(m.drainSettings)
+}
+
+// optionsTitle returns the title of the options screen
+func (m model) optionsTitle() string {
+	if isDeleteAction(m.action) {
+		return "Delete Options"
+	}
+	return "Drain Options"
+}
+
+// selectedNodeNames returns the names of the selected nodes in order
+func (m model) selectedNodeNames() []string {
+	names := make([]string, 0, len(m.selectedNodes))
+	for name := range m.selectedNodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// blastRadiusSelector returns the pod selector limiting the pods m.action
+// removes from the node
+func (m model) blastRadiusSelector() string {
+	if m.action == ActionForceDrainNode {
+		return m.drainSettings.PodSelector
+	}
+	return ""
+}
+
+// confirmCordon asks to cordon the selected node before running m.action
+func (m model) confirmCordon() (tea.Model, tea.Cmd) {
+	m.state = StateConfirmCordon
+	m.cordonNote = nil
+	taint := m.maintenanceTaint.ToString()
+	items := []list.Item{
+		item{title: ConfirmYes, desc: fmt.Sprintf("Confirm cordon node %s before %s", m.selectedNodeName, m.action)},
+		item{title: ActionTaint, desc: fmt.Sprintf("Keep node %s schedulable and apply taint %s", m.selectedNodeName, taint)},
+		item{title: ActionCordonAndTaint, desc: fmt.Sprintf("Cordon node %s and apply taint %s", m.selectedNodeName, taint)},
+		item{title: ConfirmNo, desc: DescCancelBack},
+	}
+	m.list = createList(items, "Confirm Cordon Operation", m.width, m.height)
+	return m, nil
+}
+
+// confirmCleanup asks to delete the finished pods on the selected node, which
+// stays schedulable
+func (m model) confirmCleanup() (tea.Model, tea.Cmd) {
+	m.state = StateConfirm
+	items := []list.Item{
+		item{title: ConfirmYes, desc: fmt.Sprintf("Confirm deleting the Failed, Completed and Evicted pods on node %s", m.selectedNodeName)},
+		item{title: ConfirmNo, desc: DescCancelBack},
+	}
+	m.list = createList(items, "Confirm Operation", m.width, m.height)
+	return m, nil
+}
+
+// updateEditOption handles key presses while a text drain option is edited
+func (m model) updateEditOption(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case KeyEnter:
+		if m.editingOption == optionPodSelector {
+			m.drainSettings.PodSelector = strings.TrimSpace(m.input.Value())
+		}
+		fallthrough
+	case KeyEsc:
+		m.input.Blur()
+		m.editingOption = ""
+		m.state = StateDrainOptions
+		m.list = createList(m.optionItems(), m.optionsTitle(), m.width, m.height)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
 func (m model) View() string {
 	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
 	var help string
-	if m.state == StateSelectPods {
-		help = helpStyle.Render("↑/↓: Navigate • space: Toggle select • enter: Confirm • /: Filter • q: Quit")
+	if m.state == StateEditOption {
+		help = helpStyle.Render("enter: Save • esc: Cancel")
+	} else if m.state == StateCordonReason {
+		help = helpStyle.Render("enter: Next • esc: Cancel cordon")
+	} else if m.state == StateProgress {
+		if m.progress.done {
+			help = helpStyle.Render("↑/↓: Scroll pods • pgup/pgdown: Scroll log • enter: Summary • q: Quit")
+		} else if m.gate != nil {
+			help = helpStyle.Render("↑/↓: Scroll pods • pgup/pgdown: Scroll log • r: Maintenance done • p: Pause/resume • x/ctrl+c: Cancel")
+		} else {
+			help = helpStyle.Render("↑/↓: Scroll pods • pgup/pgdown: Scroll log • p: Pause/resume • x/ctrl+c: Cancel")
+		}
+	} else if m.state == StateReport {
+		help = helpStyle.Render("↑/↓: Scroll • esc: Back to nodes • q: Quit")
+	} else if m.state == StateImpact || m.state == StateNodeDetail {
+		help = helpStyle.Render("↑/↓: Scroll • esc: Back • q: Quit")
+	} else if m.state == StateSelectPods {
+		help = helpStyle.Render("↑/↓: Navigate • space: Toggle select • a: All • n: None • i: Invert • v: Shown • N/o/P: Same namespace/owner/phase\n" +
+			"t: Table" + m.sortHelp() + " • enter: Confirm • /: Filter • q: Quit")
+	} else if err := queryError(m.list.FilterValue()); m.state == StateSelectNode && m.list.FilterState() != list.Unfiltered && err != nil {
+		// The filter matches nothing, say why in place of the key help
+		help = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(fmt.Sprintf("Invalid node query: %v", err))
 	} else if m.state == StateSelectNode {
-		help = helpStyle.Render("↑/↓: Navigate • c: Toggle cordon • enter: Select • /: Filter • q: Quit")
+		help = helpStyle.Render("↑/↓: Navigate • space: Toggle select • a: Select all shown • c: Toggle cordon • x: Clean up finished pods • d: Details • s: Sort • t: Table" + m.sortHelp() + " • enter: Select • /: Filter • q: Quit")
 	} else {
 		help = helpStyle.Render("↑/↓: Navigate • enter: Select • esc: Back • /: Filter • q: Quit")
 	}
@@ -407,11 +1416,66 @@ func (m model) View() string {
 		if len(m.list.Items()) == 0 {
 			status = m.spinner.View() + " Loading pods..."
 		}
+	case StateImpact:
+		if m.analyzing {
+			status = m.spinner.View() + " Simulating rescheduling of pods..."
+		}
+	case StateNodeDetail:
+		if m.analyzing {
+			status = m.spinner.View() + " Loading node details..."
+		}
+	case StatePreflight:
+		if len(m.list.Items()) == 0 {
+			status = m.spinner.View() + " Checking PodDisruptionBudgets..."
+		}
 	}
 
 	if status != "" {
 		return "\n" + status + "\n"
 	}
 
+	if m.state == StateProgress {
+		indicator := m.spinner.View()
+		if m.progress.done {
+			indicator = "Finished:"
+		}
+		if m.gate != nil && !m.progress.done {
+			if waiting := m.gate.pending(); len(waiting) > 0 {
+				help = fmt.Sprintf("Waiting for maintenance: %s (r releases %s)\n%s", strings.Join(waiting, ", "), waiting[0], help)
+			}
+		}
+		return "\n" + m.progress.view(indicator) + "\n" + help
+	}
+
+	if m.state == StateReport {
+		return "\n" + reportTitle(m.dryRun) + "\n\n" + m.report.View() + "\n" + help
+	}
+
+	if m.state == StateImpact {
+		return "\n" + impactTitle(m.selectedNodeName) + "\n\n" + m.report.View() + "\n" + help
+	}
+
+	if m.state == StateNodeDetail {
+		return "\n" + nodeDetailTitle(m.selectedNodeName) + "\n\n" + m.report.View() + "\n" + help
+	}
+
+	if m.state == StateEditOption {
+		return "\nPod selector (empty for all pods):\n\n" + m.input.View() + "\n\n" + help
+	}
+
+	if m.state == StateCordonReason {
+		prompt := fmt.Sprintf("Reason for cordoning node %s:", m.selectedNodeName)
+		if m.cordonNote.Reason != "" {
+			prompt = "Ticket ID (optional):"
+		}
+		return "\n" + prompt + "\n\n" + m.input.View() + "\n\n" + help
+	}
+
+	if m.notice != "" && m.state == StateSelectNode {
+		help = m.notice + "\n" + help
+	}
+	if m.tableView && (m.state == StateSelectNode || m.state == StateSelectPods) {
+		return "\n" + m.renderTable() + "\n" + help
+	}
 	return "\n" + m.list.View() + "\n" + help
 }
@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func selectionTestModel() model {
+	pods := []podInfo{
+		{name: "web-1", namespace: "shop", owner: "web-7d9f", ownerKind: "ReplicaSet", phase: "Running"},
+		{name: "web-2", namespace: "shop", owner: "web-7d9f", ownerKind: "ReplicaSet", phase: "Running"},
+		{name: "db-0", namespace: "shop", owner: "db", ownerKind: "StatefulSet", phase: "Pending"},
+		{name: "debug", namespace: "ops", phase: "Running"},
+	}
+	m := model{pods: pods, selectedPods: make(map[string]podInfo)}
+	m.list = createList(podItems(pods), "Select Pods", 80, 20)
+	return m
+}
+
+// checkSelection compares the selected keys with want, and makes sure
+// m.selectedPods and the list markers agree with the pods
+func checkSelection(t *testing.T, step string, m model, want ...string) {
+	t.Helper()
+	var got []string
+	for i, p := range m.pods {
+		if p.selected {
+			got = append(got, p.key())
+			if _, ok := m.selectedPods[p.key()]; !ok {
+				t.Errorf("%s: %s is selected but missing from selectedPods", step, p.key())
+			}
+		}
+		if item := m.list.Items()[i].(podInfo); item.selected != p.selected {
+			t.Errorf("%s: list marker of %s = %t, want %t", step, p.key(), item.selected, p.selected)
+		}
+	}
+	if len(m.selectedPods) != len(got) {
+		t.Errorf("%s: selectedPods holds %d pods, want %d", step, len(m.selectedPods), len(got))
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("%s: selected = %v, want %v", step, got, want)
+	}
+}
+
+func TestTogglePods(t *testing.T) {
+	m := selectionTestModel()
+	inShop := func(p podInfo) bool { return p.namespace == "shop" }
+
+	m.togglePods(func(p podInfo) bool { return p.name == "web-1" })
+	checkSelection(t, "toggle one", m, "shop/web-1")
+
+	// A partly selected group is selected in full first
+	m.togglePods(inShop)
+	checkSelection(t, "toggle namespace", m, "shop/web-1", "shop/web-2", "shop/db-0")
+
+	m.togglePods(func(p podInfo) bool { return sameOwner(p, m.pods[0]) })
+	checkSelection(t, "toggle owner", m, "shop/db-0")
+
+	m.togglePods(func(podInfo) bool { return true })
+	checkSelection(t, "toggle all", m, "shop/web-1", "shop/web-2", "shop/db-0", "ops/debug")
+
+	m.togglePods(inShop)
+	checkSelection(t, "toggle namespace again", m, "ops/debug")
+}
+
+func TestSelectPods(t *testing.T) {
+	m := selectionTestModel()
+	m.selectPods(func(p podInfo) bool { return p.phase == "Running" })
+	checkSelection(t, "select running", m, "shop/web-1", "shop/web-2", "ops/debug")
+
+	m.selectPods(func(p podInfo) bool { return !p.selected })
+	checkSelection(t, "invert", m, "shop/db-0")
+
+	m.selectPods(func(podInfo) bool { return false })
+	checkSelection(t, "clear", m)
+}
+
+func TestSameOwner(t *testing.T) {
+	web1 := podInfo{name: "web-1", namespace: "shop", owner: "web", ownerKind: "ReplicaSet"}
+	tests := []struct {
+		name string
+		b    podInfo
+		want bool
+	}{
+		{"same pod", web1, true},
+		{"same ReplicaSet", podInfo{name: "web-2", namespace: "shop", owner: "web", ownerKind: "ReplicaSet"}, true},
+		{"other namespace", podInfo{name: "web-1", namespace: "test", owner: "web", ownerKind: "ReplicaSet"}, false},
+		{"other kind", podInfo{name: "web-0", namespace: "shop", owner: "web", ownerKind: "StatefulSet"}, false},
+		{"bare pod", podInfo{name: "web-3", namespace: "shop"}, false},
+	}
+	for _, tt := range tests {
+		if got := sameOwner(web1, tt.b); got != tt.want {
+			t.Errorf("%s: sameOwner = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+	bare := podInfo{name: "debug", namespace: "ops"}
+	if !sameOwner(bare, bare) {
+		t.Errorf("a bare pod must be its own group")
+	}
+}
@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// checkDisruptionBudgets looks up the PodDisruptionBudgets a drain of the
+// node would run into
+func checkDisruptionBudgets(clientset *kubernetes.Clientset, nodeName, podSelector string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		pdbs, err := disruptionBudgets(ctx, clientset, nodeName, podSelector)
+		return preflightMsg{node: nodeName, pdbs: pdbs, err: err}
+	}
+}
+
+// disruptionBudgets returns every PodDisruptionBudget that matches a pod the
+// drain would evict, i.e. running pods not managed by a DaemonSet that match
+// podSelector. Budgets that block eviction are sorted first.
+func disruptionBudgets(ctx context.Context, clientset *kubernetes.Clientset, nodeName, podSelector string) ([]pdbInfo, error) {
+	selector, err := labels.Parse(podSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod selector %q: %v", podSelector, err)
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods on node %s: %v", nodeName, err)
+	}
+	byNamespace := make(map[string][]corev1.Pod)
+	for _, pod := range pods.Items {
+		if isDaemonSetPod(pod) || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		// Finished pods do not count against a budget
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		byNamespace[pod.Namespace] = append(byNamespace[pod.Namespace], pod)
+	}
+	if len(byNamespace) == 0 {
+		return nil, nil
+	}
+
+	budgets, err := clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PodDisruptionBudgets: %v", err)
+	}
+
+	var result []pdbInfo
+	for _, pdb := range budgets.Items {
+		// A nil selector matches no pods, an empty one every pod
+		pdbSelector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		info := pdbInfo{
+			name:               pdb.Name,
+			namespace:          pdb.Namespace,
+			currentHealthy:     pdb.Status.CurrentHealthy,
+			desiredHealthy:     pdb.Status.DesiredHealthy,
+			expectedPods:       pdb.Status.ExpectedPods,
+			disruptionsAllowed: pdb.Status.DisruptionsAllowed,
+		}
+		for _, pod := range byNamespace[pdb.Namespace] {
+			if pdbSelector.Matches(labels.Set(pod.Labels)) {
+				info.pods = append(info.pods, pod.Name)
+			}
+		}
+		if len(info.pods) > 0 {
+			result = append(result, info)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if (result[i].blocked() > 0) != (result[j].blocked() > 0) {
+			return result[i].blocked() > 0
+		}
+		return result[i].FilterValue() < result[j].FilterValue()
+	})
+	return result, nil
+}
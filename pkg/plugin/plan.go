@@ -0,0 +1,307 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/yaml"
+)
+
+// Actions a plan step can run
+const (
+	PlanCordon                 = "cordon"
+	PlanUncordon               = "uncordon"
+	PlanDrain                  = "drain"
+	PlanDeleteNonDaemonSetPods = "delete-non-daemonset-pods"
+	PlanDeletePods             = "delete-pods"
+	PlanCleanupFinishedPods    = "cleanup-finished-pods"
+)
+
+// Plan is a declarative maintenance plan. Its steps run in order, each on
+// its nodes in the order they are listed, or by name for a selector.
+type Plan struct {
+	// ContinueOnFailure keeps running later steps after a node failed
+	ContinueOnFailure bool       `json:"continueOnFailure,omitempty"`
+	Steps             []PlanStep `json:"steps"`
+}
+
+// PlanStep runs one action on the nodes picked by name or by label selector
+type PlanStep struct {
+	Name     string   `json:"name,omitempty"`
+	Nodes    []string `json:"nodes,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+	Action   string   `json:"action"`
+	// Drain overrides the default drain options of a drain step
+	Drain *PlanDrainOptions `json:"drain,omitempty"`
+	// Pods picks the pods a delete-pods step deletes on every node
+	Pods *PlanPods `json:"pods,omitempty"`
+	// Delete overrides how a delete step removes pods
+	Delete *PlanDeleteOptions `json:"delete,omitempty"`
+}
+
+// PlanDrainOptions mirrors the drain flags; unset fields keep their defaults
+type PlanDrainOptions struct {
+	Force                    *bool            `json:"force,omitempty"`
+	GracePeriod              *int             `json:"gracePeriod,omitempty"`
+	Timeout                  *metav1.Duration `json:"timeout,omitempty"`
+	DeleteEmptyDirData       *bool            `json:"deleteEmptyDirData,omitempty"`
+	IgnoreDaemonSets         *bool            `json:"ignoreDaemonSets,omitempty"`
+	PodSelector              string           `json:"podSelector,omitempty"`
+	SkipWaitForDeleteTimeout *int             `json:"skipWaitForDeleteTimeout,omitempty"`
+	DisableEviction          *bool            `json:"disableEviction,omitempty"`
+}
+
+// PlanDeleteOptions mirrors DeleteSettings; unset fields keep their defaults
+type PlanDeleteOptions struct {
+	Method  string           `json:"method,omitempty"`
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// PlanPods is a subset of the pods on a node. A pod must match every field
+// that is set.
+type PlanPods struct {
+	Namespace string `json:"namespace,omitempty"`
+	Selector  string `json:"selector,omitempty"`
+	// Names are pod names, or namespace/name
+	Names []string `json:"names,omitempty"`
+}
+
+// LoadPlan reads a plan from a YAML file, or from stdin when path is "-"
+func LoadPlan(path string) (*Plan, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %v", err)
+	}
+	return ParsePlan(data)
+}
+
+// ParsePlan decodes and validates a YAML plan. Unknown fields are rejected
+// so a typo does not silently fall back to a default.
+func ParsePlan(data []byte) (*Plan, error) {
+	var plan Plan
+	if err := yaml.UnmarshalStrict(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %v", err)
+	}
+	if err := plan.validate(); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (p *Plan) validate() error {
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("plan has no steps")
+	}
+	for i, step := range p.Steps {
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("step %s: %v", step.label(i), err)
+		}
+	}
+	return nil
+}
+
+func (s PlanStep) validate() error {
+	if len(s.Nodes) > 0 && s.Selector != "" {
+		return fmt.Errorf("cannot specify both nodes and a selector")
+	}
+	if len(s.Nodes) == 0 && s.Selector == "" {
+		return fmt.Errorf("nodes or a selector is required")
+	}
+	switch s.Action {
+	case PlanCordon, PlanUncordon, PlanDrain, PlanDeleteNonDaemonSetPods, PlanDeletePods, PlanCleanupFinishedPods:
+	default:
+		return fmt.Errorf("unknown action %q, must be one of %s", s.Action,
+			strings.Join([]string{PlanCordon, PlanUncordon, PlanDrain, PlanDeleteNonDaemonSetPods, PlanDeletePods, PlanCleanupFinishedPods}, ", "))
+	}
+	if s.Drain != nil && s.Action != PlanDrain {
+		return fmt.Errorf("drain options only apply to the %s action", PlanDrain)
+	}
+	if s.Delete != nil {
+		if s.Action != PlanDeletePods && s.Action != PlanDeleteNonDaemonSetPods {
+			return fmt.Errorf("delete options only apply to the %s and %s actions", PlanDeletePods, PlanDeleteNonDaemonSetPods)
+		}
+		if s.Delete.Method != "" {
+			if err := ValidateDeleteMethod(s.Delete.Method); err != nil {
+				return err
+			}
+		}
+	}
+	if s.Action == PlanDeletePods {
+		if s.Pods == nil || (s.Pods.Namespace == "" && s.Pods.Selector == "" && len(s.Pods.Names) == 0) {
+			return fmt.Errorf("the %s action needs pods with a namespace, selector or names", PlanDeletePods)
+		}
+		if _, err := labels.Parse(s.Pods.Selector); err != nil {
+			return fmt.Errorf("invalid pod selector: %v", err)
+		}
+	} else if s.Pods != nil {
+		return fmt.Errorf("pods only apply to the %s action", PlanDeletePods)
+	}
+	return nil
+}
+
+// label names the step in messages, falling back to its position
+func (s PlanStep) label(index int) string {
+	if s.Name != "" {
+		return fmt.Sprintf("%d (%s)", index+1, s.Name)
+	}
+	return fmt.Sprint(index + 1)
+}
+
+// settings applies the options on top of the default drain settings
+func (o *PlanDrainOptions) settings() DrainSettings {
+	s := DefaultDrainSettings()
+	if o == nil {
+		return s
+	}
+	if o.Force != nil {
+		s.Force = *o.Force
+	}
+	if o.GracePeriod != nil {
+		s.GracePeriodSeconds = *o.GracePeriod
+	}
+	if o.Timeout != nil {
+		s.Timeout = o.Timeout.Duration
+	}
+	if o.DeleteEmptyDirData != nil {
+		s.DeleteEmptyDirData = *o.DeleteEmptyDirData
+	}
+	if o.IgnoreDaemonSets != nil {
+		s.IgnoreAllDaemonSets = *o.IgnoreDaemonSets
+	}
+	s.PodSelector = o.PodSelector
+	if o.SkipWaitForDeleteTimeout != nil {
+		s.SkipWaitForDeleteTimeoutSeconds = *o.SkipWaitForDeleteTimeout
+	}
+	if o.DisableEviction != nil {
+		s.DisableEviction = *o.DisableEviction
+	}
+	return s
+}
+
+// settings applies the options on top of the default delete settings
+func (o *PlanDeleteOptions) settings() DeleteSettings {
+	s := DefaultDeleteSettings()
+	if o == nil {
+		return s
+	}
+	if o.Method != "" {
+		s.Method = o.Method
+	}
+	if o.Timeout != nil {
+		s.Timeout = o.Timeout.Duration
+	}
+	return s
+}
+
+// ApplyPlan runs the steps of the plan in order and writes a line per step
+// to out. Nodes are resolved when their step starts, so earlier steps can
+// change what a selector matches. Unless the plan continues on failure, a
+// failed node stops the plan after its step; a dry run always renders every
+// step.
+func (p *Plugin) ApplyPlan(ctx context.Context, plan *Plan, out io.Writer) ([]NodeResult, error) {
+	ctx, flush := p.withRecorders(ctx)
+	defer flush()
+	var results []NodeResult
+	for i, step := range plan.Steps {
+		if ctx.Err() != nil {
+			fmt.Fprintf(out, "Step %s skipped: %v\n", step.label(i), ctx.Err())
+			continue
+		}
+
+		nodes, err := resolveNodes(ctx, p.clientset, step.Nodes, step.Selector)
+		if err != nil {
+			return results, fmt.Errorf("step %s: %v", step.label(i), err)
+		}
+		names := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			names = append(names, node.Name)
+		}
+		fmt.Fprintf(out, "Step %s: %s on %s\n", step.label(i), step.Action, strings.Join(names, ", "))
+
+		stepResults := p.runStep(ctx, step, nodes)
+		results = append(results, stepResults...)
+		if CountFailed(stepResults) > 0 && !plan.ContinueOnFailure && p.dryRun == cmdutil.DryRunNone {
+			for j := i + 1; j < len(plan.Steps); j++ {
+				fmt.Fprintf(out, "Step %s not started, step %s failed\n", plan.Steps[j].label(j), step.label(i))
+			}
+			break
+		}
+	}
+	return results, nil
+}
+
+// runStep runs the action of a step through the same functions as the TUI
+func (p *Plugin) runStep(ctx context.Context, step PlanStep, nodes []corev1.Node) []NodeResult {
+	opts := []DrainerOption{WithContext(ctx), WithDryRunStrategy(p.dryRun)}
+	return runStep(ctx, p.clientset, step, nodes, opts, nil, nil)
+}
+
+// runStep runs a step with a drainer built from opts, which carry the
+// context, dry run and output of the caller
+func runStep(ctx context.Context, clientset *kubernetes.Clientset, step PlanStep, nodes []corev1.Node, opts []DrainerOption, progress progressFunc, ctrl *control) []NodeResult {
+	drainer := newDrainer(clientset, opts...)
+	dryRun := drainer.DryRunStrategy
+	switch step.Action {
+	case PlanCordon, PlanUncordon:
+		return cordonNodes(drainer, nodes, step.Action == PlanCordon)
+	case PlanDrain:
+		drainer = newDrainer(clientset, append(step.Drain.settings().Options(), opts...)...)
+		return drainNodes(drainer, nodes, progress, ctrl)
+	case PlanDeleteNonDaemonSetPods:
+		return cordonAndRun(drainer, nodes, ActionForceDeleteNonDS, func(node *corev1.Node) NodeResult {
+			return deleteNonDaemonSetPods(ctx, clientset, node.Name, step.Delete.settings(), dryRun, progress, ctrl)
+		})
+	case PlanCleanupFinishedPods:
+		return cleanupNodes(ctx, clientset, nodeNames(nodes), dryRun, progress, ctrl)
+	default:
+		return cordonAndRun(drainer, nodes, ActionForceDeleteSelected, func(node *corev1.Node) NodeResult {
+			pods, err := planPods(ctx, clientset, node.Name, step.Pods)
+			if err != nil {
+				return NodeResult{Node: node.Name, Action: ActionForceDeleteSelected, Status: ResultFailed, Message: err.Error()}
+			}
+			return deleteSelectedPods(ctx, clientset, node.Name, pods, step.Delete.settings(), dryRun, progress, ctrl)
+		})
+	}
+}
+
+// planPods lists the pods on the node that match the subset
+func planPods(ctx context.Context, clientset *kubernetes.Clientset, nodeName string, subset *PlanPods) ([]podInfo, error) {
+	selector, err := labels.Parse(subset.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod selector: %v", err)
+	}
+	podList, err := clientset.CoreV1().Pods(subset.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods on node %s: %v", nodeName, err)
+	}
+
+	names := make(map[string]bool, len(subset.Names))
+	for _, name := range subset.Names {
+		names[name] = true
+	}
+	var pods []podInfo
+	for _, pod := range podList.Items {
+		if len(names) > 0 && !names[pod.Name] && !names[pod.Namespace+"/"+pod.Name] {
+			continue
+		}
+		pods = append(pods, newPodInfo(pod))
+	}
+	return pods, nil
+}
@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePlan(t *testing.T) {
+	plan, err := ParsePlan([]byte(`
+continueOnFailure: true
+steps:
+- name: drain workers
+  selector: node-role.kubernetes.io/worker=
+  action: drain
+  drain:
+    timeout: 5m
+    ignoreDaemonSets: false
+- nodes: [node-a]
+  action: delete-pods
+  pods:
+    namespace: batch
+  delete:
+    method: evict-then-force
+- nodes: [node-a, node-b]
+  action: uncordon
+`))
+	if err != nil {
+		t.Fatalf("ParsePlan: %v", err)
+	}
+	if !plan.ContinueOnFailure || len(plan.Steps) != 3 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	drain := plan.Steps[0].Drain.settings()
+	if drain.Timeout != 5*time.Minute || drain.IgnoreAllDaemonSets {
+		t.Errorf("drain options not applied: %+v", drain)
+	}
+	if !drain.Force {
+		t.Error("unset drain options must keep their defaults")
+	}
+	if method := plan.Steps[1].Delete.settings().Method; method != DeleteEvictThenForce {
+		t.Errorf("delete method = %q, want %q", method, DeleteEvictThenForce)
+	}
+}
+
+func TestParsePlanRejectsUnknownFields(t *testing.T) {
+	for _, data := range []string{
+		"steps:\n- nodes: [node-a]\n  action: drain\n  drian:\n    force: false\n",
+		"steps:\n- nodes: [node-a]\n  action: drain\n  drain:\n    forse: false\n",
+		"continueOnFailures: true\nsteps:\n- nodes: [node-a]\n  action: cordon\n",
+	} {
+		if _, err := ParsePlan([]byte(data)); err == nil || !strings.Contains(err.Error(), "failed to parse plan") {
+			t.Errorf("ParsePlan(%q) = %v, want a parse error", data, err)
+		}
+	}
+}
+
+func TestPlanValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		step PlanStep
+		err  string
+	}{
+		{"cordon", PlanStep{Nodes: []string{"node-a"}, Action: PlanCordon}, ""},
+		{"cleanup", PlanStep{Selector: "pool=batch", Action: PlanCleanupFinishedPods}, ""},
+		{"nodes and selector", PlanStep{Nodes: []string{"node-a"}, Selector: "pool=batch", Action: PlanCordon}, "both nodes and a selector"},
+		{"no nodes", PlanStep{Action: PlanCordon}, "nodes or a selector is required"},
+		{"unknown action", PlanStep{Nodes: []string{"node-a"}, Action: "reboot"}, `unknown action "reboot"`},
+		{"drain options", PlanStep{Nodes: []string{"node-a"}, Action: PlanCordon, Drain: &PlanDrainOptions{}}, "drain options only apply"},
+		{"delete options", PlanStep{Nodes: []string{"node-a"}, Action: PlanDrain, Delete: &PlanDeleteOptions{}}, "delete options only apply"},
+		{"delete method", PlanStep{Nodes: []string{"node-a"}, Action: PlanDeleteNonDaemonSetPods, Delete: &PlanDeleteOptions{Method: "shred"}}, "shred"},
+		{"no pods", PlanStep{Nodes: []string{"node-a"}, Action: PlanDeletePods}, "needs pods"},
+		{"empty pods", PlanStep{Nodes: []string{"node-a"}, Action: PlanDeletePods, Pods: &PlanPods{}}, "needs pods"},
+		{"pod selector", PlanStep{Nodes: []string{"node-a"}, Action: PlanDeletePods, Pods: &PlanPods{Selector: "app in (x"}}, "invalid pod selector"},
+		{"pods", PlanStep{Nodes: []string{"node-a"}, Action: PlanDrain, Pods: &PlanPods{Namespace: "batch"}}, "pods only apply"},
+	}
+	for _, tt := range tests {
+		plan := Plan{Steps: []PlanStep{tt.step}}
+		err := plan.validate()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: error = %v, want one containing %q", tt.name, err, tt.err)
+		}
+	}
+
+	if err := (&Plan{}).validate(); err == nil {
+		t.Error("a plan without steps must not validate")
+	}
+	plan := Plan{Steps: []PlanStep{{Nodes: []string{"node-a"}, Action: PlanCordon}, {Name: "oops", Action: PlanCordon}}}
+	if err := plan.validate(); err == nil || !strings.HasPrefix(err.Error(), "step 2 (oops):") {
+		t.Errorf("error = %v, want it to name step 2 (oops)", err)
+	}
+}
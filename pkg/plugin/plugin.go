@@ -1,31 +1,154 @@
 package plugin
 
 import (
+	"context"
+	"fmt"
+	"os"
+
 	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 )
 
 type Plugin struct {
-	clientset *kubernetes.Clientset
+	clientset        *kubernetes.Clientset
+	drainSettings    DrainSettings
+	deleteSettings   DeleteSettings
+	dryRun           cmdutil.DryRunStrategy
+	operator         *operator
+	audit            *auditLog
+	events           *eventRecorder
+	recordEvents     bool
+	maintenanceTaint corev1.Taint
+	nodeFilter       NodeFilter
+}
+
+// Option configures a Plugin
+type Option func(*Plugin)
+
+// WithDrainSettings sets the drain options the TUI starts with
+func WithDrainSettings(settings DrainSettings) Option {
+	return func(p *Plugin) {
+		p.drainSettings = settings
+	}
+}
+
+// WithDeleteSettings sets how the TUI delete actions remove pods by default
+func WithDeleteSettings(settings DeleteSettings) Option {
+	return func(p *Plugin) {
+		p.deleteSettings = settings
+	}
+}
+
+// WithDryRun makes every action report what it would change instead of
+// changing the cluster
+func WithDryRun(strategy cmdutil.DryRunStrategy) Option {
+	return func(p *Plugin) {
+		p.dryRun = strategy
+	}
+}
+
+// WithAuditLog appends every change to the JSON-lines file at path. The
+// entries name kubeContext as the kubeconfig context in use.
+func WithAuditLog(path, kubeContext string) Option {
+	return func(p *Plugin) {
+		p.audit = &auditLog{path: path, kubeContext: kubeContext}
+	}
 }
 
-func NewPlugin(config *rest.Config) (*Plugin, error) {
+// WithMaintenanceTaint sets the taint the TUI offers to apply instead of, or
+// along with, a cordon
+func WithMaintenanceTaint(taint corev1.Taint) Option {
+	return func(p *Plugin) {
+		p.maintenanceTaint = taint
+	}
+}
+
+// WithNodeFilter limits the TUI node list to the nodes matching filter
+func WithNodeFilter(filter NodeFilter) Option {
+	return func(p *Plugin) {
+		p.nodeFilter = filter
+	}
+}
+
+// WithEvents records Kubernetes Events on the nodes and pods the actions change
+func WithEvents(enabled bool) Option {
+	return func(p *Plugin) {
+		p.recordEvents = enabled
+	}
+}
+
+func NewPlugin(config *rest.Config, opts ...Option) (*Plugin, error) {
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, err
 	}
-	return &Plugin{
-		clientset: clientset,
-	}, nil
+	p := &Plugin{
+		clientset:        clientset,
+		drainSettings:    DefaultDrainSettings(),
+		deleteSettings:   DefaultDeleteSettings(),
+		maintenanceTaint: DefaultMaintenanceTaint(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.operator = &operator{client: clientset}
+	if p.audit != nil {
+		p.audit.operator = p.operator
+	}
+	if p.recordEvents {
+		p.events = newEventRecorder(clientset, p.operator)
+	}
+	return p, nil
 }
 
-func (p *Plugin) Run() error {
+// withRecorders attaches the audit log and the event recorder to ctx. The
+// returned func waits for queued Events and warns about failed ones.
+func (p *Plugin) withRecorders(ctx context.Context) (context.Context, func()) {
+	ctx = withEvents(withAudit(ctx, p.audit), p.events)
+	return ctx, func() {
+		if p.events == nil {
+			return
+		}
+		if err := p.events.flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %v\n", err)
+		}
+	}
+}
+
+// Run starts the TUI. Canceling ctx cancels a running action, and the TUI
+// exits once the action has stopped, printing what it got done.
+func (p *Plugin) Run(ctx context.Context) error {
+	ctx, flush := p.withRecorders(ctx)
+	defer flush()
+	m := initialModel(ctx, p)
 	program := tea.NewProgram(
-		initialModel(p.clientset),
+		m,
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
+		tea.WithoutSignalHandler(),
 	)
-	_, err := program.Run()
-	return err
+	m.watcher.start(program.Send)
+	defer m.watcher.stop()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			program.Send(interruptMsg{})
+		case <-done:
+		}
+	}()
+
+	final, err := program.Run()
+	if err != nil {
+		return err
+	}
+	if fm, ok := final.(model); ok && fm.quitAfterOp && len(fm.results) > 0 {
+		fmt.Fprintln(os.Stdout, formatReport(fm.results, fm.dryRun))
+	}
+	return ctx.Err()
 }
@@ -0,0 +1,257 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// Output formats accepted by -o/--output
+const (
+	OutputTable         = ""
+	OutputWide          = "wide"
+	OutputJSON          = "json"
+	OutputYAML          = "yaml"
+	OutputJSONPath      = "jsonpath"
+	OutputCustomColumns = "custom-columns"
+)
+
+// NodeSummary is the machine-readable form of a node row
+type NodeSummary struct {
+	Name        string   `json:"name"`
+	Status      string   `json:"status"`
+	Schedulable bool     `json:"schedulable"`
+	Roles       []string `json:"roles"`
+	Age         string   `json:"age"`
+	Version     string   `json:"version"`
+	InternalIP  string   `json:"internalIP"`
+	Conditions  []string `json:"conditions"`
+	Usage       *Usage   `json:"usage,omitempty"`
+	Pods        *int     `json:"pods,omitempty"`
+}
+
+// Usage is the CPU and memory in use as reported by metrics.k8s.io, with the
+// share of node allocatable for nodes
+type Usage struct {
+	CPU           string `json:"cpu"`
+	CPUPercent    *int64 `json:"cpuPercent,omitempty"`
+	Memory        string `json:"memory"`
+	MemoryPercent *int64 `json:"memoryPercent,omitempty"`
+}
+
+// PodSummary is the machine-readable form of a pod row
+type PodSummary struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Node      string `json:"node"`
+	Owner     string `json:"owner"`
+	OwnerKind string `json:"ownerKind"`
+	Phase     string `json:"phase"`
+	Age       string `json:"age"`
+	Usage     *Usage `json:"usage,omitempty"`
+}
+
+// itemList wraps printed items so that JSONPath templates can use .items
+type itemList[T any] struct {
+	Kind  string `json:"kind"`
+	Items []T    `json:"items"`
+}
+
+// PrintNodes writes nodes in the given output format
+func PrintNodes(w io.Writer, nodes []NodeSummary, output string) error {
+	return printItems(w, itemList[NodeSummary]{Kind: "NodeSummaryList", Items: nodes}, output, func(tw io.Writer, wide bool) {
+		header := "NAME\tSTATUS\tSCHEDULABLE\tROLES\tAGE\tVERSION"
+		if wide {
+			header += "\tINTERNAL-IP\tCONDITIONS\tPODS\tCPU\tMEMORY"
+		}
+		fmt.Fprintln(tw, header)
+		for _, n := range nodes {
+			row := fmt.Sprintf("%s\t%s\t%t\t%s\t%s\t%s", n.Name, n.Status, n.Schedulable, strings.Join(n.Roles, ","), n.Age, n.Version)
+			if wide {
+				pods := "<unknown>"
+				if n.Pods != nil {
+					pods = fmt.Sprintf("%d", *n.Pods)
+				}
+				row += fmt.Sprintf("\t%s\t%s\t%s\t%s\t%s", valueOrNone(n.InternalIP), valueOrNone(strings.Join(n.Conditions, ",")),
+					pods, n.Usage.cpuText(), n.Usage.memoryText())
+			}
+			fmt.Fprintln(tw, row)
+		}
+	})
+}
+
+// PrintPods writes pods in the given output format
+func PrintPods(w io.Writer, pods []PodSummary, output string) error {
+	return printItems(w, itemList[PodSummary]{Kind: "PodSummaryList", Items: pods}, output, func(tw io.Writer, wide bool) {
+		header := "NAMESPACE\tNAME\tPHASE\tOWNER\tAGE"
+		if wide {
+			header += "\tNODE\tCPU\tMEMORY"
+		}
+		fmt.Fprintln(tw, header)
+		for _, p := range pods {
+			owner := "<none>"
+			if p.Owner != "" {
+				owner = fmt.Sprintf("%s/%s", p.OwnerKind, p.Owner)
+			}
+			row := fmt.Sprintf("%s\t%s\t%s\t%s\t%s", p.Namespace, p.Name, p.Phase, owner, p.Age)
+			if wide {
+				row += fmt.Sprintf("\t%s\t%s\t%s", p.Node, p.Usage.cpuText(), p.Usage.memoryText())
+			}
+			fmt.Fprintln(tw, row)
+		}
+	})
+}
+
+// cpuText and memoryText render a usage column, <unknown> without metrics
+func (u *Usage) cpuText() string {
+	if u == nil {
+		return "<unknown>"
+	}
+	return percentText(u.CPU, u.CPUPercent)
+}
+
+func (u *Usage) memoryText() string {
+	if u == nil {
+		return "<unknown>"
+	}
+	return percentText(u.Memory, u.MemoryPercent)
+}
+
+func percentText(value string, percent *int64) string {
+	if percent == nil {
+		return value
+	}
+	return fmt.Sprintf("%s (%d%%)", value, *percent)
+}
+
+// printItems dispatches on the output format; table renders the plain and
+// wide table layouts
+func printItems[T any](w io.Writer, list itemList[T], output string, table func(w io.Writer, wide bool)) error {
+	format, arg, _ := strings.Cut(output, "=")
+	switch format {
+	case OutputTable, OutputWide:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		table(tw, format == OutputWide)
+		return tw.Flush()
+	case OutputJSON:
+		data, err := json.MarshalIndent(list, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case OutputYAML:
+		data, err := yaml.Marshal(list)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case OutputJSONPath:
+		return printJSONPath(w, list, arg)
+	case OutputCustomColumns:
+		return printCustomColumns(w, list.Items, arg)
+	}
+	return fmt.Errorf("unsupported output format %q, must be one of: wide, json, yaml, jsonpath=..., custom-columns=...", output)
+}
+
+// printJSONPath evaluates a JSONPath template against the whole list
+func printJSONPath[T any](w io.Writer, list itemList[T], template string) error {
+	if template == "" {
+		return fmt.Errorf("jsonpath template must not be empty")
+	}
+	data, err := toGeneric(list)
+	if err != nil {
+		return err
+	}
+	jp := jsonpath.New("output").AllowMissingKeys(true)
+	if err := jp.Parse(template); err != nil {
+		return fmt.Errorf("error parsing jsonpath %s: %v", template, err)
+	}
+	if err := jp.Execute(w, data); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
+
+// printCustomColumns renders a kubectl-style custom-columns spec, e.g.
+// NAME:.name,STATUS:.status
+func printCustomColumns[T any](w io.Writer, items []T, spec string) error {
+	if spec == "" {
+		return fmt.Errorf("custom-columns format specified but no custom columns given")
+	}
+
+	var headers []string
+	var parsers []*jsonpath.JSONPath
+	for _, column := range strings.Split(spec, ",") {
+		header, path, ok := strings.Cut(column, ":")
+		if !ok {
+			return fmt.Errorf("unexpected custom-columns spec: %s, expected <header>:<json-path-expr>", column)
+		}
+		jp := jsonpath.New(header).AllowMissingKeys(true)
+		if err := jp.Parse(relaxedJSONPath(path)); err != nil {
+			return fmt.Errorf("error parsing jsonpath %s: %v", path, err)
+		}
+		headers = append(headers, header)
+		parsers = append(parsers, jp)
+	}
+
+	generic, err := toGeneric(items)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, item := range generic.([]interface{}) {
+		values := make([]string, 0, len(parsers))
+		for _, jp := range parsers {
+			var buf bytes.Buffer
+			if err := jp.Execute(&buf, item); err != nil {
+				return err
+			}
+			values = append(values, valueOrNone(buf.String()))
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+	return tw.Flush()
+}
+
+// relaxedJSONPath accepts both ".field" and "{.field}" like kubectl does
+func relaxedJSONPath(path string) string {
+	path = strings.TrimSpace(path)
+	if strings.HasPrefix(path, "{") && strings.HasSuffix(path, "}") {
+		return path
+	}
+	if !strings.HasPrefix(path, ".") {
+		path = "." + path
+	}
+	return "{" + path + "}"
+}
+
+// toGeneric round-trips v through JSON so JSONPath sees the json field names
+func toGeneric(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,268 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Pod states shown in the progress view
+const (
+	PodPending  = "pending"
+	PodEvicting = "evicting"
+	PodDeleting = "deleting"
+	PodEvicted  = "evicted"
+	PodDeleted  = "deleted"
+	PodFailed   = "failed"
+	PodSkipped  = "skipped"
+)
+
+// podEvent reports a state change of one pod during a running action
+type podEvent struct {
+	namespace string
+	name      string
+	status    string
+	err       error
+}
+
+// progressFunc receives pod events; a nil progressFunc discards them
+type progressFunc func(podEvent)
+
+func (f progressFunc) report(namespace, name, status string, err error) {
+	if f != nil {
+		f(podEvent{namespace: namespace, name: name, status: status, err: err})
+	}
+}
+
+// progressMsg carries either a pod event or a line of log output from a
+// running action into the TUI
+type progressMsg struct {
+	event *podEvent
+	line  string
+}
+
+// startOperation runs fn in the background and returns the channel its
+// progress is streamed on. The last message is the resultsMsg of fn.
+func startOperation(fn func(progress progressFunc, log io.Writer) []NodeResult) <-chan tea.Msg {
+	events := make(chan tea.Msg, 64)
+	go func() {
+		defer close(events)
+		log := &lineWriter{events: events}
+		results := fn(func(e podEvent) { events <- progressMsg{event: &e} }, log)
+		log.flush()
+		events <- resultsMsg(results)
+	}()
+	return events
+}
+
+// waitForOperation delivers the next message of a running action
+func waitForOperation(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// lineWriter turns writes into one progressMsg per line. drain.Helper writes
+// from several goroutines at once, hence the lock.
+type lineWriter struct {
+	mu     sync.Mutex
+	events chan<- tea.Msg
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.events <- progressMsg{line: string(w.buf[:i])}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.events <- progressMsg{line: string(w.buf)}
+		w.buf = nil
+	}
+}
+
+// podRow is one line of the progress view
+type podRow struct {
+	pod    string
+	status string
+	err    string
+}
+
+// progressView tracks the pods and log output of a running action
+type progressView struct {
+	title   string
+	events  <-chan tea.Msg
+	rows    []podRow
+	index   map[string]int
+	logs    []string
+	podView viewport.Model
+	logView viewport.Model
+	done    bool
+	// paused and canceling only change the header; the action itself is
+	// driven through its control
+	paused    bool
+	canceling bool
+}
+
+func newProgressView(title string, events <-chan tea.Msg, width, height int) progressView {
+	p := progressView{
+		title:  title,
+		events: events,
+		index:  make(map[string]int),
+	}
+	p.setSize(width, height)
+	return p
+}
+
+// progressChrome is the number of lines around the two viewports
+const progressChrome = 8
+
+func (p *progressView) setSize(width, height int) {
+	h, v := lipgloss.NewStyle().Margin(1, 2).GetFrameSize()
+	avail := height - v - progressChrome
+	if avail < 2 {
+		avail = 2
+	}
+	podHeight := avail / 2
+	p.podView = resizedViewport(p.podView, width-h, podHeight)
+	p.logView = resizedViewport(p.logView, width-h, avail-podHeight)
+	p.refresh()
+}
+
+func resizedViewport(vp viewport.Model, width, height int) viewport.Model {
+	if vp.Width == 0 && vp.Height == 0 {
+		return viewport.New(width, height)
+	}
+	vp.Width = width
+	vp.Height = height
+	return vp
+}
+
+// apply records a progress message
+func (p *progressView) apply(msg progressMsg) {
+	if msg.event == nil {
+		p.logs = append(p.logs, msg.line)
+		p.refresh()
+		return
+	}
+
+	e := msg.event
+	key := e.namespace + "/" + e.name
+	row := podRow{pod: key, status: e.status}
+	if e.err != nil {
+		row.err = e.err.Error()
+	}
+	if i, ok := p.index[key]; ok {
+		p.rows[i] = row
+	} else {
+		p.index[key] = len(p.rows)
+		p.rows = append(p.rows, row)
+	}
+	p.refresh()
+}
+
+// failPending marks pods that never finished as failed, e.g. after the
+// action returned an error before reaching them
+func (p *progressView) failPending(reason string) {
+	for i := range p.rows {
+		switch p.rows[i].status {
+		case PodEvicted, PodDeleted, PodFailed, PodSkipped:
+		default:
+			p.rows[i].status = PodFailed
+			p.rows[i].err = reason
+		}
+	}
+	p.refresh()
+}
+
+func (p *progressView) refresh() {
+	followLog := p.logView.AtBottom()
+	var b strings.Builder
+	for _, r := range p.rows {
+		fmt.Fprintf(&b, "%s %-9s %s", podStatusIcon(r.status), r.status, r.pod)
+		if r.err != "" {
+			fmt.Fprintf(&b, ": %s", r.err)
+		}
+		b.WriteString("\n")
+	}
+	p.podView.SetContent(b.String())
+	p.logView.SetContent(strings.Join(p.logs, "\n"))
+	if followLog {
+		p.logView.GotoBottom()
+	}
+}
+
+// counts returns the number of finished, failed and skipped pods
+func (p progressView) counts() (done, failed, skipped int) {
+	for _, r := range p.rows {
+		switch r.status {
+		case PodEvicted, PodDeleted:
+			done++
+		case PodFailed:
+			done++
+			failed++
+		case PodSkipped:
+			skipped++
+		}
+	}
+	return done, failed, skipped
+}
+
+func (p progressView) view(spinner string) string {
+	done, failed, skipped := p.counts()
+	header := fmt.Sprintf("%s %s  %d/%d pods done", spinner, p.title, done, len(p.rows))
+	if failed > 0 {
+		header += fmt.Sprintf(", %d failed", failed)
+	}
+	if skipped > 0 {
+		header += fmt.Sprintf(", %d skipped", skipped)
+	}
+	switch {
+	case p.done:
+	case p.canceling:
+		header += "  [canceling, waiting for pods in flight]"
+	case p.paused:
+		header += "  [paused after the current pods]"
+	}
+	sectionStyle := lipgloss.NewStyle().Bold(true)
+	return header + "\n\n" +
+		sectionStyle.Render("Pods") + "\n" + p.podView.View() + "\n\n" +
+		sectionStyle.Render("Log") + "\n" + p.logView.View()
+}
+
+func podStatusIcon(status string) string {
+	switch status {
+	case PodEvicted, PodDeleted:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Render("✓")
+	case PodFailed:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("✗")
+	case PodEvicting, PodDeleting:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render("…")
+	case PodSkipped:
+		return "-"
+	}
+	return "·"
+}
@@ -0,0 +1,367 @@
+package plugin
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// NodeQuery is a parsed node query: space-separated terms that must all
+// match, e.g. "role=worker status=NotReady age>30d version<1.29
+// label:zone=us-east-1a cordoned". A term is
+//
+//   - field op value, with field one of name, status, role, ip, version, age,
+//     cpu or memory and op one of = != > >= < <=; name, status, role and ip
+//     only take = and != and match * wildcards, version compares the given
+//     components only, age takes 45m, 12h or 30d, cpu and memory take the
+//     usage share of allocatable in percent
+//   - label:key, label:key=value or label:key!=value
+//   - cordoned, schedulable, tainted, ready or notready
+//   - any other word, matched as part of the node name
+//
+// A leading ! negates a term.
+type NodeQuery struct {
+	terms []queryTerm
+}
+
+type queryTerm struct {
+	negate bool
+	match  func(n nodeInfo) bool
+}
+
+var (
+	fieldTermPattern = regexp.MustCompile(`^([a-z]+)(!=|>=|<=|=|>|<)(.*)$`)
+	labelTermPattern = regexp.MustCompile(`^label:([^=!]+)(?:(!=|=)(.*))?$`)
+)
+
+// queryKeywords are the terms that test a node state
+var queryKeywords = map[string]func(n nodeInfo) bool{
+	"cordoned":    func(n nodeInfo) bool { return !n.schedulable },
+	"schedulable": func(n nodeInfo) bool { return n.schedulable },
+	"tainted":     func(n nodeInfo) bool { return n.taint != "" },
+	"ready":       func(n nodeInfo) bool { return n.status == "Ready" },
+	"notready":    func(n nodeInfo) bool { return n.status != "Ready" },
+}
+
+// ParseNodeQuery parses a node query, see NodeQuery
+func ParseNodeQuery(query string) (NodeQuery, error) {
+	var q NodeQuery
+	for _, word := range strings.Fields(query) {
+		term, err := parseQueryTerm(word)
+		if err != nil {
+			return NodeQuery{}, err
+		}
+		q.terms = append(q.terms, term)
+	}
+	return q, nil
+}
+
+func parseQueryTerm(word string) (queryTerm, error) {
+	term := queryTerm{}
+	if strings.HasPrefix(word, "!") && !strings.HasPrefix(word, "!=") {
+		term.negate = true
+		word = word[1:]
+	}
+
+	if m := labelTermPattern.FindStringSubmatch(word); m != nil {
+		key, op, value := m[1], m[2], m[3]
+		term.match = func(n nodeInfo) bool {
+			v, ok := n.labels[key]
+			switch op {
+			case "=":
+				return ok && v == value
+			case "!=":
+				return !ok || v != value
+			}
+			return ok
+		}
+		return term, nil
+	}
+
+	if m := fieldTermPattern.FindStringSubmatch(word); m != nil {
+		match, err := fieldMatcher(m[1], m[2], m[3])
+		if err != nil {
+			return queryTerm{}, err
+		}
+		term.match = match
+		return term, nil
+	}
+
+	if keyword, ok := queryKeywords[strings.ToLower(word)]; ok {
+		term.match = keyword
+		return term, nil
+	}
+
+	if strings.ContainsAny(word, "=<>:") {
+		return queryTerm{}, fmt.Errorf("invalid query term %q", word)
+	}
+	term.match = func(n nodeInfo) bool { return strings.Contains(n.name, word) }
+	return term, nil
+}
+
+// fieldMatcher builds the test for a field op value term
+func fieldMatcher(field, op, value string) (func(n nodeInfo) bool, error) {
+	switch field {
+	case "name", "status", "role", "ip":
+		if op != "=" && op != "!=" {
+			return nil, fmt.Errorf("invalid operator %s for %s, must be = or !=", op, field)
+		}
+		if _, err := path.Match(value, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q for %s: %v", value, field, err)
+		}
+		return func(n nodeInfo) bool {
+			var values []string
+			switch field {
+			case "name":
+				values = []string{n.name}
+			case "status":
+				values = []string{n.status}
+			case "role":
+				values = n.roles
+			case "ip":
+				values = []string{n.internal}
+			}
+			return matchAny(values, value) == (op == "=")
+		}, nil
+
+	case "version":
+		want, err := parseVersion(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q: %v", value, err)
+		}
+		return func(n nodeInfo) bool {
+			have, err := parseVersion(n.version)
+			if err != nil {
+				return false
+			}
+			return compare(compareVersions(have, want), op)
+		}, nil
+
+	case "age":
+		want, err := parseAge(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age %q: %v", value, err)
+		}
+		return func(n nodeInfo) bool {
+			return compare(compareInt64(int64(n.age), int64(want)), op)
+		}, nil
+
+	case "cpu", "memory":
+		want, err := strconv.ParseInt(strings.TrimSuffix(value, "%"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s usage %q, must be a percentage", field, value)
+		}
+		return func(n nodeInfo) bool {
+			have := n.usagePercent(field)
+			return have >= 0 && compare(compareInt64(have, want), op)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown query field %q, must be one of: name, status, role, ip, version, age, cpu, memory", field)
+}
+
+// Matches reports whether the node matches every term
+func (q NodeQuery) Matches(n nodeInfo) bool {
+	for _, term := range q.terms {
+		if term.match(n) == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// isStructuredQuery reports whether a filter uses the query syntax rather
+// than a plain fuzzy search on the node name
+func isStructuredQuery(filter string) bool {
+	for _, word := range strings.Fields(filter) {
+		word = strings.TrimPrefix(word, "!")
+		if _, ok := queryKeywords[strings.ToLower(word)]; ok || strings.ContainsAny(word, "=<>:") {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(values []string, pattern string) bool {
+	for _, v := range values {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(v)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVersion parses the numeric components of v1.29.3 or 1.29
+func parseVersion(s string) ([]int, error) {
+	s = strings.TrimPrefix(s, "v")
+	// Drop pre-release and build metadata, e.g. v1.29.3-eks-1234 or +k3s1
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	var parts []int
+	for _, p := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("must be like 1.29 or v1.29.3")
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// compareVersions compares have with want on the components want gives, so
+// that 1.29.3 equals 1.29
+func compareVersions(have, want []int) int {
+	for i, w := range want {
+		var h int
+		if i < len(have) {
+			h = have[i]
+		}
+		if c := compareInt64(int64(h), int64(w)); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// parseAge parses a duration that may also be given in days, like 30d
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compare applies op to the result of a comparison
+func compare(c int, op string) bool {
+	switch op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
+
+// nodeIndex lets the list filter, which only sees the node names, evaluate
+// queries against the nodes shown. The filter runs off the update loop.
+type nodeIndex struct {
+	mu    sync.Mutex
+	nodes map[string]nodeInfo
+}
+
+func (x *nodeIndex) set(nodes []nodeInfo) {
+	index := make(map[string]nodeInfo, len(nodes))
+	for _, n := range nodes {
+		index[n.name] = n
+	}
+	x.mu.Lock()
+	x.nodes = index
+	x.mu.Unlock()
+}
+
+// filter is the list.FilterFunc of the node list: a query is matched against
+// the nodes, anything else is a fuzzy search on the name. A query that does
+// not parse matches no node, the view shows why.
+func (x *nodeIndex) filter(term string, targets []string) []list.Rank {
+	if !isStructuredQuery(term) {
+		return list.DefaultFilter(term, targets)
+	}
+	q, err := ParseNodeQuery(term)
+	if err != nil {
+		return nil
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var ranks []list.Rank
+	for i, name := range targets {
+		if n, ok := x.nodes[name]; ok && q.Matches(n) {
+			ranks = append(ranks, list.Rank{Index: i})
+		}
+	}
+	return ranks
+}
+
+// queryError returns why the filter term does not parse as a node query, nil
+// for a valid query or a fuzzy search
+func queryError(term string) error {
+	if !isStructuredQuery(term) {
+		return nil
+	}
+	_, err := ParseNodeQuery(term)
+	return err
+}
+
+// NodeFilter picks the nodes shown: a label selector, evaluated by the API
+// server where possible, and a query on the node fields
+type NodeFilter struct {
+	selector labels.Selector
+	query    NodeQuery
+}
+
+// NewNodeFilter parses a label selector and a node query, both may be empty
+func NewNodeFilter(selector, query string) (NodeFilter, error) {
+	s, err := labels.Parse(selector)
+	if err != nil {
+		return NodeFilter{}, fmt.Errorf("invalid selector %q: %v", selector, err)
+	}
+	q, err := ParseNodeQuery(query)
+	if err != nil {
+		return NodeFilter{}, fmt.Errorf("invalid node query %q: %v", query, err)
+	}
+	return NodeFilter{selector: s, query: q}, nil
+}
+
+// labelSelector returns the selector to send with node list requests
+func (f NodeFilter) labelSelector() string {
+	if f.selector == nil {
+		return ""
+	}
+	return f.selector.String()
+}
+
+func (f NodeFilter) matches(n nodeInfo) bool {
+	if f.selector != nil && !f.selector.Matches(labels.Set(n.labels)) {
+		return false
+	}
+	return f.query.Matches(n)
+}
+
+// filterNodes returns the nodes that match f
+func (f NodeFilter) filterNodes(nodes []nodeInfo) []nodeInfo {
+	matched := nodes[:0]
+	for _, n := range nodes {
+		if f.matches(n) {
+			matched = append(matched, n)
+		}
+	}
+	return matched
+}
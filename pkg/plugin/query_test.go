@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseNodeQueryErrors(t *testing.T) {
+	for _, query := range []string{
+		"age>>3d",
+		"age>x",
+		"version<1.x",
+		"cpu>lots",
+		"role>worker",
+		"name=[",
+		"zone=us-east-1a",
+		"foo:bar",
+	} {
+		if _, err := ParseNodeQuery(query); err == nil {
+			t.Errorf("ParseNodeQuery(%q) succeeded, want an error", query)
+		}
+	}
+}
+
+func TestNodeQueryMatches(t *testing.T) {
+	worker := nodeInfo{
+		name:              "worker-1",
+		status:            "Ready",
+		schedulable:       true,
+		roles:             []string{"worker"},
+		age:               40 * 24 * time.Hour,
+		version:           "v1.28.5-eks-5e0fdde",
+		internal:          "10.0.0.7",
+		labels:            map[string]string{"zone": "us-east-1a"},
+		allocatableCPU:    resource.MustParse("4"),
+		allocatableMemory: resource.MustParse("16Gi"),
+		usage:             &resourceUsage{cpu: resource.MustParse("3400m"), memory: resource.MustParse("4Gi")},
+	}
+	cordoned := nodeInfo{
+		name:    "control-1",
+		status:  "NotReady",
+		roles:   []string{"control-plane"},
+		age:     2 * time.Hour,
+		version: "v1.29.3",
+		taint:   "maintenance=true:NoSchedule",
+		labels:  map[string]string{"zone": "us-east-1b"},
+	}
+
+	tests := []struct {
+		query    string
+		worker   bool
+		cordoned bool
+	}{
+		{"", true, true},
+		{"worker", true, false},
+		{"!worker", false, true},
+		{"name=worker-*", true, false},
+		{"name!=worker-*", false, true},
+		{"status=notready", false, true},
+		{"role=control-plane", false, true},
+		{"ip=10.0.0.*", true, false},
+		{"version<1.29", true, false},
+		{"version=1.29", false, true},
+		{"version>=1.28.5", true, true},
+		{"age>30d", true, false},
+		{"age<=3h", false, true},
+		{"cpu>80", true, false},
+		{"memory<50", true, false},
+		{"label:zone", true, true},
+		{"label:zone=us-east-1a", true, false},
+		{"label:zone!=us-east-1a", false, true},
+		{"label:rack", false, false},
+		{"cordoned", false, true},
+		{"schedulable ready", true, false},
+		{"tainted", false, true},
+		{"!tainted notready", false, false},
+	}
+	for _, tt := range tests {
+		q, err := ParseNodeQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseNodeQuery(%q): %v", tt.query, err)
+		}
+		if got := q.Matches(worker); got != tt.worker {
+			t.Errorf("%q matches worker = %t, want %t", tt.query, got, tt.worker)
+		}
+		if got := q.Matches(cordoned); got != tt.cordoned {
+			t.Errorf("%q matches cordoned = %t, want %t", tt.query, got, tt.cordoned)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		have, want []int
+		result     int
+	}{
+		{[]int{1, 29, 3}, []int{1, 29}, 0},
+		{[]int{1, 29, 3}, []int{1, 29, 3}, 0},
+		{[]int{1, 29, 3}, []int{1, 29, 4}, -1},
+		{[]int{1, 30}, []int{1, 29, 9}, 1},
+		{[]int{1, 9}, []int{1, 10}, -1},
+		{[]int{1}, []int{1, 0}, 0},
+		{[]int{1}, []int{1, 1}, -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.have, tt.want); got != tt.result {
+			t.Errorf("compareVersions(%v, %v) = %d, want %d", tt.have, tt.want, got, tt.result)
+		}
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	if err := queryError("worker"); err != nil {
+		t.Errorf("a fuzzy search is not a query: %v", err)
+	}
+	if err := queryError("role=worker age>3d"); err != nil {
+		t.Errorf("valid query: %v", err)
+	}
+	if err := queryError("age>>3d"); err == nil {
+		t.Error("invalid query parsed")
+	}
+}
@@ -0,0 +1,80 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Result statuses reported for each node
+const (
+	ResultSucceeded = "Succeeded"
+	ResultUnchanged = "Unchanged"
+	ResultFailed    = "Failed"
+	ResultCanceled  = "Canceled"
+)
+
+// Change records a single change made to the cluster, or that would be made
+// on a dry run
+type Change struct {
+	Action string `json:"action"`
+	Object string `json:"object"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NodeResult records the outcome of a maintenance action on a single node
+type NodeResult struct {
+	Node    string
+	Action  string
+	Status  string
+	Message string
+	Changes []Change
+}
+
+// Failed reports whether the action on the node failed or was canceled
+// before it finished
+func (r NodeResult) Failed() bool {
+	return r.Status == ResultFailed || r.Status == ResultCanceled
+}
+
+// failedResults marks every node as failed with the same error
+func failedResults(names []string, action string, err error) []NodeResult {
+	results := make([]NodeResult, 0, len(names))
+	for _, name := range names {
+		results = append(results, NodeResult{Node: name, Action: action, Status: ResultFailed, Message: err.Error()})
+	}
+	return results
+}
+
+// CountFailed returns the number of failed results
+func CountFailed(results []NodeResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Failed() {
+			failed++
+		}
+	}
+	return failed
+}
+
+// CountCanceled returns the number of results canceled before they finished
+func CountCanceled(results []NodeResult) int {
+	canceled := 0
+	for _, r := range results {
+		if r.Status == ResultCanceled {
+			canceled++
+		}
+	}
+	return canceled
+}
+
+// PrintNodeResults writes a per-node summary table to w
+func PrintNodeResults(w io.Writer, results []NodeResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tACTION\tRESULT\tMESSAGE")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Node, r.Action, r.Status, r.Message)
+	}
+	return tw.Flush()
+}
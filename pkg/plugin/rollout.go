@@ -0,0 +1,310 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+// RolloutSettings configures a rolling maintenance of several nodes
+type RolloutSettings struct {
+	// MaxUnavailable is the number of nodes taken out of service at once
+	MaxUnavailable int
+	// ReadyTimeout bounds the wait for an uncordoned node to report Ready
+	ReadyTimeout time.Duration
+}
+
+// DefaultRolloutSettings takes one node at a time out of service
+func DefaultRolloutSettings() RolloutSettings {
+	return RolloutSettings{
+		MaxUnavailable: 1,
+		ReadyTimeout:   10 * time.Minute,
+	}
+}
+
+// optionMaxUnavailable identifies the max unavailable option in the TUI
+const optionMaxUnavailable = "maxUnavailable"
+
+// maxUnavailableChoices are the presets cycled through in the TUI
+var maxUnavailableChoices = []int{1, 2, 3, 5}
+
+// MaintenanceGate blocks while maintenance runs on a drained node and returns
+// once the node may be put back into service
+type MaintenanceGate func(ctx context.Context, nodeName string) error
+
+// CommandGate runs command through sh every interval until it exits 0. The
+// node name is passed in the NODE_NAME environment variable.
+func CommandGate(command string, interval time.Duration, out io.Writer) MaintenanceGate {
+	return func(ctx context.Context, nodeName string) error {
+		for {
+			cmd := exec.CommandContext(ctx, "sh", "-c", command)
+			cmd.Env = append(os.Environ(), "NODE_NAME="+nodeName)
+			err := cmd.Run()
+			if err == nil {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Fprintf(out, "node/%s not ready yet (%v), checking again in %s\n", nodeName, err, interval)
+
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// PromptGate asks the operator on out to press enter on in once maintenance
+// of a node is done. Nodes are prompted for one at a time.
+func PromptGate(in io.Reader, out io.Writer) MaintenanceGate {
+	var mu sync.Mutex
+	lines := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- struct{}{}
+		}
+		close(lines)
+	}()
+
+	return func(ctx context.Context, nodeName string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(out, "node/%s is drained. Press enter once its maintenance is done: ", nodeName)
+		select {
+		case _, ok := <-lines:
+			if !ok {
+				return fmt.Errorf("no more input while waiting for node %s", nodeName)
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Rollout takes the given nodes out of service a few at a time: each node is
+// cordoned and drained, released by gate once its maintenance is done, then
+// uncordoned and waited for until it reports Ready again. Nodes are picked
+// either by name or by label selector.
+func (p *Plugin) Rollout(ctx context.Context, names []string, selector string, drainSettings DrainSettings, settings RolloutSettings, gate MaintenanceGate) ([]NodeResult, error) {
+	ctx, flush := p.withRecorders(ctx)
+	defer flush()
+	nodes, err := resolveNodes(ctx, p.clientset, names, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := append(drainSettings.Options(), WithContext(ctx), WithDryRunStrategy(p.dryRun))
+	return rolloutNodes(ctx, p.clientset, nodes, opts, settings, gate, nil, nil), nil
+}
+
+// rolloutNodes runs the rollout with up to settings.MaxUnavailable nodes in
+// maintenance at once. The first failed node stops the rollout, so no further
+// node is taken out of service; nodes not started are reported as canceled.
+func rolloutNodes(ctx context.Context, clientset *kubernetes.Clientset, nodes []corev1.Node, opts []DrainerOption, settings RolloutSettings, gate MaintenanceGate, progress progressFunc, ctrl *control) []NodeResult {
+	results := make([]NodeResult, len(nodes))
+	workers := settings.MaxUnavailable
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	var failedNode string
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				mu.Lock()
+				stoppedBy := failedNode
+				mu.Unlock()
+				result := NodeResult{Node: nodes[i].Name, Action: MsgRollout, Status: ResultCanceled}
+				switch {
+				case stoppedBy != "":
+					result.Message = fmt.Sprintf("not started, the rollout stopped after node %s failed", stoppedBy)
+				case ctrl.wait(ctx) != nil:
+					result.Message = "canceled before the node was touched"
+				default:
+					// Each node gets its own drainer, the drain callbacks are per node
+					drainer := newDrainer(clientset, opts...)
+					result = rolloutNode(ctx, clientset, drainer, &nodes[i], settings, gate, progress)
+					if result.Failed() {
+						mu.Lock()
+						if failedNode == "" {
+							failedNode = nodes[i].Name
+						}
+						mu.Unlock()
+					}
+				}
+				results[i] = result
+			}
+		}()
+	}
+
+	for i := range nodes {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+// rolloutNode takes a single node through cordon, drain, maintenance,
+// uncordon and the wait for NodeReady. A node that fails after the cordon is
+// left cordoned. A node that was already cordoned or tainted for maintenance
+// before the rollout stays so, along with its cordon note.
+func rolloutNode(ctx context.Context, clientset *kubernetes.Clientset, drainer *drain.Helper, node *corev1.Node, settings RolloutSettings, gate MaintenanceGate, progress progressFunc) NodeResult {
+	result := NodeResult{Node: node.Name, Action: MsgRollout}
+	keepCordoned := inMaintenance(node)
+
+	cordon := cordonNode(drainer, node, true, nil)
+	result.Changes = append(result.Changes, cordon.Changes...)
+	if cordon.Failed() {
+		result.Status = cordon.Status
+		result.Message = fmt.Sprintf("failed to cordon node: %s", cordon.Message)
+		return result
+	}
+
+	drained := rolloutDrain(drainer, node.Name, progress)
+	result.Changes = append(result.Changes, drained.Changes...)
+	if drained.Failed() {
+		result.Status = drained.Status
+		result.Message = drained.Message + "; node left cordoned"
+		return result
+	}
+
+	if drainer.DryRunStrategy != cmdutil.DryRunNone {
+		result.Status = ResultSucceeded
+		if keepCordoned {
+			result.Message = fmt.Sprintf("%s, would wait for maintenance; cordoned before the rollout, would stay cordoned", drained.Message)
+			return result
+		}
+		uncordon := cordonNode(drainer, node, false, nil)
+		result.Changes = append(result.Changes, uncordon.Changes...)
+		result.Message = fmt.Sprintf("%s, would wait for maintenance and uncordon", drained.Message)
+		return result
+	}
+
+	fmt.Fprintf(drainer.Out, "node/%s drained, waiting for its maintenance to finish\n", node.Name)
+	if err := gate(ctx, node.Name); err != nil {
+		result.Status = ResultFailed
+		if ctx.Err() != nil {
+			result.Status = ResultCanceled
+		}
+		result.Message = fmt.Sprintf("stopped waiting for maintenance: %v; node left cordoned", err)
+		return result
+	}
+	if keepCordoned {
+		result.Status = ResultSucceeded
+		result.Message = fmt.Sprintf("%s, maintained; cordoned before the rollout, left cordoned", drained.Message)
+		return result
+	}
+
+	// Maintenance may have changed the node, e.g. a reboot
+	fresh, err := clientset.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+	if err != nil {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("failed to get node after maintenance: %v; node left cordoned", err)
+		return result
+	}
+	uncordon := cordonNode(drainer, fresh, false, nil)
+	result.Changes = append(result.Changes, uncordon.Changes...)
+	if uncordon.Failed() {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("failed to uncordon node: %s", uncordon.Message)
+		return result
+	}
+
+	fmt.Fprintf(drainer.Out, "node/%s uncordoned, waiting for it to become Ready\n", node.Name)
+	if err := waitForNodeReady(ctx, clientset, node.Name, settings.ReadyTimeout); err != nil {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("node did not become Ready: %v", err)
+		return result
+	}
+	fmt.Fprintf(drainer.Out, "node/%s is Ready\n", node.Name)
+
+	result.Status = ResultSucceeded
+	result.Message = fmt.Sprintf("%s, maintained and back in service", drained.Message)
+	return result
+}
+
+// rolloutDrain drains a cordoned node with drain.RunNodeDrain, reporting its
+// pods to progress through the drainer callbacks. Unlike drainNode it cannot
+// be paused halfway, a rollout pauses and cancels between nodes.
+func rolloutDrain(drainer *drain.Helper, nodeName string, progress progressFunc) NodeResult {
+	result := NodeResult{Node: nodeName, Action: MsgDrain}
+	if drainer.DryRunStrategy != cmdutil.DryRunNone {
+		return dryRunDrain(drainer, result)
+	}
+
+	var mu sync.Mutex
+	drainer.OnPodDeletionOrEvictionStarted = func(pod *corev1.Pod, usingEviction bool) {
+		status := PodDeleting
+		if usingEviction {
+			status = PodEvicting
+		}
+		progress.report(pod.Namespace, pod.Name, status, nil)
+	}
+	drainer.OnPodDeletionOrEvictionFinished = func(pod *corev1.Pod, usingEviction bool, err error) {
+		status, action := PodDeleted, "delete"
+		if usingEviction {
+			status, action = PodEvicted, "evict"
+		}
+		change := Change{Action: action, Object: fmt.Sprintf("pod/%s/%s", pod.Namespace, pod.Name)}
+		if err != nil {
+			status = PodFailed
+			change.Error = err.Error()
+		}
+		progress.report(pod.Namespace, pod.Name, status, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		result.Changes = append(result.Changes, change)
+	}
+
+	err := drain.RunNodeDrain(drainer, nodeName)
+	mu.Lock()
+	defer mu.Unlock()
+	switch {
+	case err != nil && drainer.Ctx.Err() != nil:
+		result.Status = ResultCanceled
+		result.Message = fmt.Sprintf("canceled: %v", err)
+	case err != nil:
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("failed to drain node: %v", err)
+	default:
+		result.Status = ResultSucceeded
+		result.Message = "drained"
+	}
+	return recordResult(drainer.Ctx, result, auditOptions{drain: drainOptionsOf(drainer)})
+}
+
+// waitForNodeReady polls the node until its NodeReady condition is true
+func waitForNodeReady(ctx context.Context, clientset *kubernetes.Clientset, nodeName string, timeout time.Duration) error {
+	return wait.PollUntilContextTimeout(ctx, 5*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			// Keep polling, the API server may be briefly unreachable
+			return false, nil
+		}
+		return isNodeReady(node), nil
+	})
+}
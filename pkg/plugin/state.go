@@ -0,0 +1,333 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+// operationAnnotation holds the progress of a running operation on the node
+// itself, so an operation cut short by a dropped session can be resumed from
+// any machine
+const operationAnnotation = "node-maintain.futuretea.io/operation"
+
+// OperationState is the persisted progress of a drain or deletion on a node.
+// Actions use the plan action names.
+type OperationState struct {
+	Node      string             `json:"node"`
+	Action    string             `json:"action"`
+	Started   metav1.Time        `json:"started"`
+	Drain     *PlanDrainOptions  `json:"drain,omitempty"`
+	Delete    *PlanDeleteOptions `json:"delete,omitempty"`
+	Handled   []string           `json:"handled,omitempty"`
+	Remaining []string           `json:"remaining,omitempty"`
+}
+
+// Title, Description and FilterValue list the operation in the TUI
+func (s OperationState) Title() string {
+	return fmt.Sprintf("%s: unfinished %s", s.Node, s.Action)
+}
+
+func (s OperationState) Description() string {
+	return fmt.Sprintf("Started %s ago | %s", formatDuration(time.Since(s.Started.Time)), s.summary())
+}
+
+func (s OperationState) FilterValue() string {
+	return s.Node
+}
+
+// summary counts the pods handled before the operation stopped
+func (s OperationState) summary() string {
+	return fmt.Sprintf("%d of %d pods handled", len(s.Handled), len(s.Handled)+len(s.Remaining))
+}
+
+// operationOf reads the persisted operation of a node, if any
+func operationOf(node *corev1.Node) *OperationState {
+	value, ok := node.Annotations[operationAnnotation]
+	if !ok {
+		return nil
+	}
+	var state OperationState
+	if err := json.Unmarshal([]byte(value), &state); err != nil {
+		return nil
+	}
+	state.Node = node.Name
+	return &state
+}
+
+// progressSaveInterval is the least time between two saves of the progress
+// of an operation, finished pods in between are saved together
+const progressSaveInterval = time.Second
+
+// operationTracker persists the progress of one operation on its node. The
+// annotation is removed once the operation finished, and kept when it failed
+// or was canceled so that it can be resumed. A nil tracker records nothing.
+type operationTracker struct {
+	client   kubernetes.Interface
+	mu       sync.Mutex
+	state    OperationState
+	dirty    bool
+	saving   bool
+	lastSave time.Time
+	saveErr  error
+}
+
+// trackOperation records that the operation starts on the given pods. There
+// is nothing to resume without pods, so no tracker is returned then.
+func trackOperation(client kubernetes.Interface, state OperationState, pods []string) *operationTracker {
+	if len(pods) == 0 {
+		return nil
+	}
+	state.Started = metav1.Now()
+	state.Remaining = append([]string(nil), pods...)
+	t := &operationTracker{client: client, state: state, dirty: true}
+	t.save()
+	return t
+}
+
+// wrap returns a progressFunc that records finished pods before passing the
+// event on to progress
+func (t *operationTracker) wrap(progress progressFunc) progressFunc {
+	if t == nil {
+		return progress
+	}
+	return func(e podEvent) {
+		if e.status == PodEvicted || e.status == PodDeleted {
+			t.handled(e.namespace + "/" + e.name)
+		}
+		progress.report(e.namespace, e.name, e.status, e.err)
+	}
+}
+
+// handled moves the pod to the handled ones. The state is saved at most once
+// per progressSaveInterval and never by two pods at once, the pods finished
+// meanwhile go with the next save or with finish.
+func (t *operationTracker) handled(pod string) {
+	t.mu.Lock()
+	for i, name := range t.state.Remaining {
+		if name == pod {
+			t.state.Remaining = append(t.state.Remaining[:i], t.state.Remaining[i+1:]...)
+			t.state.Handled = append(t.state.Handled, pod)
+			t.dirty = true
+			break
+		}
+	}
+	due := t.dirty && !t.saving && time.Since(t.lastSave) >= progressSaveInterval
+	t.mu.Unlock()
+	if due {
+		t.save()
+	}
+}
+
+// finish clears the persisted state unless the operation has to be resumed,
+// then the progress not saved yet is written. Progress that could not be
+// saved is noted in the result.
+func (t *operationTracker) finish(result NodeResult) NodeResult {
+	if t == nil {
+		return result
+	}
+	if result.Failed() {
+		t.save()
+	} else if err := clearOperation(context.Background(), t.client, t.state.Node); err != nil {
+		t.mu.Lock()
+		t.saveErr = err
+		t.mu.Unlock()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return withWarning(result, t.saveErr)
+}
+
+// save writes the state to the node unless another save is running or there
+// is nothing new. The lock is not held while writing, so pods keep finishing.
+// The operation's own context may be canceled already, so the patch gets a
+// context of its own with the timeout of patchOperation. A failed write only
+// costs the ability to resume and does not stop the operation.
+func (t *operationTracker) save() {
+	t.mu.Lock()
+	if t.saving || !t.dirty {
+		t.mu.Unlock()
+		return
+	}
+	value, err := json.Marshal(t.state)
+	t.saving, t.dirty = true, false
+	t.mu.Unlock()
+
+	if err == nil {
+		err = patchOperation(context.Background(), t.client, t.state.Node, string(value))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.saving = false
+	t.lastSave = time.Now()
+	if err != nil && t.saveErr == nil {
+		t.saveErr = fmt.Errorf("failed to save progress on node %s: %v", t.state.Node, err)
+	}
+}
+
+// clearOperation removes the persisted operation from the node
+func clearOperation(ctx context.Context, client kubernetes.Interface, nodeName string) error {
+	if err := patchOperation(ctx, client, nodeName, nil); err != nil {
+		return fmt.Errorf("failed to clear progress on node %s: %v", nodeName, err)
+	}
+	return nil
+}
+
+// patchOperation sets the operation annotation, or removes it for nil
+func patchOperation(ctx context.Context, client kubernetes.Interface, nodeName string, value interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{operationAnnotation: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// drainOptionsOf records the options of a drainer for a later resume
+func drainOptionsOf(drainer *drain.Helper) *PlanDrainOptions {
+	force, deleteEmptyDir, ignoreDaemonSets, disableEviction :=
+		drainer.Force, drainer.DeleteEmptyDirData, drainer.IgnoreAllDaemonSets, drainer.DisableEviction
+	gracePeriod, skipWait := drainer.GracePeriodSeconds, drainer.SkipWaitForDeleteTimeoutSeconds
+	return &PlanDrainOptions{
+		Force:                    &force,
+		GracePeriod:              &gracePeriod,
+		Timeout:                  &metav1.Duration{Duration: drainer.Timeout},
+		DeleteEmptyDirData:       &deleteEmptyDir,
+		IgnoreDaemonSets:         &ignoreDaemonSets,
+		PodSelector:              drainer.PodSelector,
+		SkipWaitForDeleteTimeout: &skipWait,
+		DisableEviction:          &disableEviction,
+	}
+}
+
+// deleteOptionsOf records the delete settings for a later resume
+func deleteOptionsOf(settings DeleteSettings) *PlanDeleteOptions {
+	return &PlanDeleteOptions{Method: settings.Method, Timeout: &metav1.Duration{Duration: settings.Timeout}}
+}
+
+// podKeys returns namespace/name of each pod
+func podKeys(pods []corev1.Pod) []string {
+	keys := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		keys = append(keys, pod.Namespace+"/"+pod.Name)
+	}
+	return keys
+}
+
+// UnfinishedOperations lists the operations persisted on the nodes, limited
+// to the given nodes when names are given
+func (p *Plugin) UnfinishedOperations(ctx context.Context, names []string) ([]OperationState, error) {
+	nodeList, err := p.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	var ops []OperationState
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if len(wanted) > 0 && !wanted[node.Name] {
+			continue
+		}
+		if op := operationOf(node); op != nil {
+			ops = append(ops, *op)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Node < ops[j].Node })
+	return ops, nil
+}
+
+// Resume picks up the given operations where they stopped
+func (p *Plugin) Resume(ctx context.Context, ops []OperationState) []NodeResult {
+	ctx, flush := p.withRecorders(ctx)
+	defer flush()
+	opts := []DrainerOption{WithContext(ctx), WithDryRunStrategy(p.dryRun)}
+	return resumeOperations(ctx, p.clientset, ops, opts, nil, nil)
+}
+
+// Discard forgets the given operations without running them
+func (p *Plugin) Discard(ctx context.Context, ops []OperationState) error {
+	for _, op := range ops {
+		if err := clearOperation(ctx, p.clientset, op.Node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resumeOperations runs each operation again as a plan step. A drain or a
+// deletion of all non-DaemonSet pods simply runs again on the pods left, a
+// deletion of selected pods continues with the pods not handled yet.
+func resumeOperations(ctx context.Context, clientset *kubernetes.Clientset, ops []OperationState, opts []DrainerOption, progress progressFunc, ctrl *control) []NodeResult {
+	dryRun := newDrainer(clientset, opts...).DryRunStrategy
+	var results []NodeResult
+	for _, op := range ops {
+		step := PlanStep{Nodes: []string{op.Node}, Action: op.Action, Drain: op.Drain, Delete: op.Delete}
+		if op.Action == PlanDeletePods && len(op.Remaining) == 0 {
+			// Every pod was handled, only the state was left behind
+			result := NodeResult{Node: op.Node, Action: ActionForceDeleteSelected, Status: ResultSucceeded, Message: "no pods left to delete"}
+			if dryRun == cmdutil.DryRunNone {
+				if err := clearOperation(ctx, clientset, op.Node); err != nil {
+					result.Status, result.Message = ResultFailed, err.Error()
+				}
+			}
+			results = append(results, result)
+			continue
+		}
+		if op.Action == PlanDeletePods {
+			step.Pods = &PlanPods{Names: op.Remaining}
+		}
+		if err := step.validate(); err != nil {
+			results = append(results, NodeResult{Node: op.Node, Action: op.Action, Status: ResultFailed,
+				Message: fmt.Sprintf("cannot resume: %v", err)})
+			continue
+		}
+
+		nodes, err := resolveNodes(ctx, clientset, step.Nodes, "")
+		if err != nil {
+			results = append(results, failedResults(step.Nodes, op.Action, err)...)
+			continue
+		}
+		// The run only tracks pods it finds, so a node that has none left
+		// still carries the old state
+		for _, result := range runStep(ctx, clientset, step, nodes, opts, progress, ctrl) {
+			if !result.Failed() && dryRun == cmdutil.DryRunNone {
+				result = withWarning(result, clearOperation(ctx, clientset, op.Node))
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// PrintOperations writes a table of unfinished operations
+func PrintOperations(w io.Writer, ops []OperationState) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tACTION\tSTARTED\tPROGRESS")
+	for _, op := range ops {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", op.Node, op.Action, op.Started.Format(time.RFC3339), op.summary())
+	}
+	return tw.Flush()
+}
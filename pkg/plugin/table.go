@@ -0,0 +1,360 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Columns the node list can be sorted by; the keys double as --sort-by values
+const (
+	SortByName    = "name"
+	SortByStatus  = "status"
+	SortByRoles   = "roles"
+	SortByAge     = "age"
+	SortByVersion = "version"
+	SortByIP      = "ip"
+	SortByPods    = "pods"
+	SortByCPU     = "cpu"
+	SortByMemory  = "memory"
+)
+
+// NodeSorts lists the orders the s key cycles through in the node list
+var NodeSorts = []string{SortByName, SortByCPU, SortByMemory}
+
+// tableSort is the column a list is ordered by
+type tableSort struct {
+	column string
+	desc   bool
+}
+
+// defaultSort orders by column, usage busiest first and the rest ascending
+func defaultSort(column string) tableSort {
+	return tableSort{column: column, desc: column == SortByCPU || column == SortByMemory}
+}
+
+// tableColumn is one column of the table view. Columns with a lower keep are
+// dropped first when the terminal is too narrow; a zero width column takes
+// the space that is left.
+type tableColumn struct {
+	key   string
+	title string
+	width int
+	keep  int
+	cell  func(it list.Item) string
+	less  func(a, b list.Item) bool
+}
+
+// markerWidth is the selection marker in front of every row
+const markerWidth = 4
+
+var nodeColumns = []tableColumn{
+	{key: SortByName, title: "NAME", keep: 9,
+		cell: func(it list.Item) string { return it.(nodeInfo).name },
+		less: func(a, b list.Item) bool { return a.(nodeInfo).name < b.(nodeInfo).name }},
+	{key: SortByStatus, title: "STATUS", width: 25, keep: 8,
+		cell: func(it list.Item) string { return it.(nodeInfo).statusText() },
+		less: func(a, b list.Item) bool { return a.(nodeInfo).statusText() < b.(nodeInfo).statusText() }},
+	{key: SortByRoles, title: "ROLES", width: 14, keep: 3,
+		cell: func(it list.Item) string { return strings.Join(it.(nodeInfo).roles, ",") },
+		less: func(a, b list.Item) bool {
+			return strings.Join(a.(nodeInfo).roles, ",") < strings.Join(b.(nodeInfo).roles, ",")
+		}},
+	{key: SortByAge, title: "AGE", width: 6, keep: 5,
+		cell: func(it list.Item) string { return formatDuration(it.(nodeInfo).age) },
+		less: func(a, b list.Item) bool { return a.(nodeInfo).age < b.(nodeInfo).age }},
+	{key: SortByVersion, title: "VERSION", width: 12, keep: 4,
+		cell: func(it list.Item) string { return it.(nodeInfo).version },
+		less: func(a, b list.Item) bool {
+			va, _ := parseVersion(a.(nodeInfo).version)
+			vb, _ := parseVersion(b.(nodeInfo).version)
+			return compareVersions(va, vb) < 0
+		}},
+	{key: SortByIP, title: "IP", width: 15, keep: 1,
+		cell: func(it list.Item) string { return valueOrNone(it.(nodeInfo).internal) },
+		less: func(a, b list.Item) bool { return a.(nodeInfo).internal < b.(nodeInfo).internal }},
+	{key: SortByPods, title: "PODS", width: 7, keep: 2,
+		cell: func(it list.Item) string { return it.(nodeInfo).podCountText() },
+		less: func(a, b list.Item) bool { return a.(nodeInfo).podCount < b.(nodeInfo).podCount }},
+	{key: SortByCPU, title: "CPU", width: 12, keep: 7,
+		cell: func(it list.Item) string { return it.(nodeInfo).usageCell(SortByCPU) },
+		less: func(a, b list.Item) bool {
+			return a.(nodeInfo).usagePercent(SortByCPU) < b.(nodeInfo).usagePercent(SortByCPU)
+		}},
+	{key: SortByMemory, title: "MEMORY", width: 14, keep: 6,
+		cell: func(it list.Item) string { return it.(nodeInfo).usageCell(SortByMemory) },
+		less: func(a, b list.Item) bool {
+			return a.(nodeInfo).usagePercent(SortByMemory) < b.(nodeInfo).usagePercent(SortByMemory)
+		}},
+}
+
+var podColumns = []tableColumn{
+	{key: "namespace", title: "NAMESPACE", width: 16, keep: 7,
+		cell: func(it list.Item) string { return it.(podInfo).namespace },
+		less: func(a, b list.Item) bool { return a.(podInfo).namespace < b.(podInfo).namespace }},
+	{key: "name", title: "NAME", keep: 9,
+		cell: func(it list.Item) string { return it.(podInfo).name },
+		less: func(a, b list.Item) bool { return a.(podInfo).name < b.(podInfo).name }},
+	{key: "phase", title: "PHASE", width: 10, keep: 8,
+		cell: func(it list.Item) string { return it.(podInfo).phase },
+		less: func(a, b list.Item) bool { return a.(podInfo).phase < b.(podInfo).phase }},
+	{key: "owner", title: "OWNER", width: 28, keep: 3,
+		cell: func(it list.Item) string { return it.(podInfo).ownerText() },
+		less: func(a, b list.Item) bool { return a.(podInfo).ownerText() < b.(podInfo).ownerText() }},
+	{key: "age", title: "AGE", width: 6, keep: 6,
+		cell: func(it list.Item) string { return formatDuration(it.(podInfo).age) },
+		less: func(a, b list.Item) bool { return a.(podInfo).age < b.(podInfo).age }},
+	{key: "cpu", title: "CPU", width: 7, keep: 5,
+		cell: func(it list.Item) string { return it.(podInfo).usageCell(SortByCPU) },
+		less: func(a, b list.Item) bool {
+			return a.(podInfo).usageValue(SortByCPU) < b.(podInfo).usageValue(SortByCPU)
+		}},
+	{key: "memory", title: "MEMORY", width: 9, keep: 4,
+		cell: func(it list.Item) string { return it.(podInfo).usageCell(SortByMemory) },
+		less: func(a, b list.Item) bool {
+			return a.(podInfo).usageValue(SortByMemory) < b.(podInfo).usageValue(SortByMemory)
+		}},
+}
+
+// ValidateNodeSort checks a column the node list can be sorted by
+func ValidateNodeSort(by string) error {
+	if findColumn(nodeColumns, by) < 0 {
+		keys := make([]string, len(nodeColumns))
+		for i, c := range nodeColumns {
+			keys[i] = c.key
+		}
+		return fmt.Errorf("invalid sort %q, must be one of: %s", by, strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// nextNodeSort returns the order after order in NodeSorts
+func nextNodeSort(order tableSort) tableSort {
+	for i, s := range NodeSorts {
+		if s == order.column {
+			return defaultSort(NodeSorts[(i+1)%len(NodeSorts)])
+		}
+	}
+	return defaultSort(SortByName)
+}
+
+// sortByColumn sorts by the column with index i, or flips the direction when
+// the list is already sorted by it
+func sortByColumn(columns []tableColumn, order tableSort, i int) tableSort {
+	if i < 0 || i >= len(columns) {
+		return order
+	}
+	if columns[i].key == order.column {
+		return tableSort{column: order.column, desc: !order.desc}
+	}
+	return defaultSort(columns[i].key)
+}
+
+func findColumn(columns []tableColumn, key string) int {
+	for i, c := range columns {
+		if c.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// sortItems orders items by a column, ties by the first column. Rows without
+// a value, like nodes without usage, sort as the lowest.
+func sortItems(items []list.Item, columns []tableColumn, order tableSort) {
+	i := findColumn(columns, order.column)
+	if i < 0 {
+		return
+	}
+	column := columns[i]
+	sort.SliceStable(items, func(a, b int) bool {
+		switch {
+		case column.less(items[a], items[b]):
+			return !order.desc
+		case column.less(items[b], items[a]):
+			return order.desc
+		}
+		return columns[0].less(items[a], items[b])
+	})
+}
+
+// sortNodes orders nodes the way the node table is sorted
+func sortNodes(nodes []nodeInfo, order tableSort) {
+	items := make([]list.Item, len(nodes))
+	for i := range nodes {
+		items[i] = nodes[i]
+	}
+	sortItems(items, nodeColumns, order)
+	for i := range items {
+		nodes[i] = items[i].(nodeInfo)
+	}
+}
+
+// sortPods orders pods the way the pod table is sorted
+func sortPods(pods []podInfo, order tableSort) {
+	items := podItems(pods)
+	sortItems(items, podColumns, order)
+	for i := range items {
+		pods[i] = items[i].(podInfo)
+	}
+}
+
+// layoutColumns returns the width of every column for a table of the given
+// width, 0 for the columns that do not fit
+func layoutColumns(columns []tableColumn, width int) []int {
+	const gap, flexMin = 2, 12
+	widths := make([]int, len(columns))
+	used := markerWidth
+	for i, c := range columns {
+		widths[i] = c.width
+		if c.width == 0 {
+			widths[i] = flexMin
+		}
+		used += widths[i] + gap
+	}
+
+	// Drop the least important columns until the rest fit
+	for used > width {
+		drop := -1
+		for i, c := range columns {
+			if widths[i] > 0 && c.width > 0 && (drop < 0 || c.keep < columns[drop].keep) {
+				drop = i
+			}
+		}
+		if drop < 0 {
+			break
+		}
+		used -= widths[drop] + gap
+		widths[drop] = 0
+	}
+
+	// The flexible column takes what is left
+	for i, c := range columns {
+		if c.width == 0 && used < width {
+			widths[i] += width - used
+		}
+	}
+	return widths
+}
+
+// renderRow lays out cells in the column widths, truncating long values
+func renderRow(cells []string, widths []int) string {
+	var b strings.Builder
+	for i, cell := range cells {
+		if widths[i] == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("  ")
+		}
+		b.WriteString(fitCell(cell, widths[i]))
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+func fitCell(s string, width int) string {
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-len(r))
+}
+
+var (
+	tableHeaderStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("245"))
+	tableRowStyle    = lipgloss.NewStyle().PaddingLeft(2)
+	tableCursorStyle = lipgloss.NewStyle().
+				Border(lipgloss.NormalBorder(), false, false, false, true).
+				BorderForeground(lipgloss.AdaptiveColor{Light: "#F793FF", Dark: "#AD58B4"}).
+				Foreground(lipgloss.AdaptiveColor{Light: "#EE6FF8", Dark: "#EE6FF8"}).
+				PaddingLeft(1)
+)
+
+// tableHeader renders the column titles, marking the sorted column with its
+// direction and numbering the columns for the sort keys
+func tableHeader(columns []tableColumn, order tableSort, width int) string {
+	widths := layoutColumns(columns, width)
+	titles := make([]string, len(columns))
+	for i, c := range columns {
+		titles[i] = fmt.Sprintf("%d:%s", i+1, c.title)
+		if c.key == order.column {
+			arrow := "▲"
+			if order.desc {
+				arrow = "▼"
+			}
+			titles[i] += arrow
+		}
+	}
+	return tableHeaderStyle.Render(strings.Repeat(" ", markerWidth) + renderRow(titles, widths))
+}
+
+// tableDelegate renders list items as one aligned row each
+type tableDelegate struct {
+	columns []tableColumn
+}
+
+func (d tableDelegate) Height() int                             { return 1 }
+func (d tableDelegate) Spacing() int                            { return 0 }
+func (d tableDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+
+func (d tableDelegate) Render(w io.Writer, m list.Model, index int, it list.Item) {
+	// The row styles add two columns of padding or border
+	widths := layoutColumns(d.columns, m.Width()-2)
+	cells := make([]string, len(d.columns))
+	for i, c := range d.columns {
+		cells[i] = c.cell(it)
+	}
+	row := fitCell(rowMarker(it), markerWidth) + renderRow(cells, widths)
+	if index == m.Index() {
+		fmt.Fprint(w, tableCursorStyle.Render(row))
+		return
+	}
+	fmt.Fprint(w, tableRowStyle.Render(row))
+}
+
+// rowMarker shows the selection state in front of a row
+func rowMarker(it list.Item) string {
+	switch it := it.(type) {
+	case nodeInfo:
+		if it.selected {
+			return "[✓]"
+		}
+		return ""
+	case podInfo:
+		if it.selected {
+			return "[✓]"
+		}
+		return "[ ]"
+	}
+	return ""
+}
+
+// tableChrome is the title, blank and header lines above the table rows
+const tableChrome = 3
+
+// setTableView switches a node or pod list between the default two-line
+// items and the table. The table draws its own title, see tableTitleView.
+func setTableView(l *list.Model, table bool, columns []tableColumn) {
+	l.SetShowTitle(!table)
+	l.SetShowFilter(!table)
+	if table {
+		l.SetDelegate(tableDelegate{columns: columns})
+		return
+	}
+	l.SetDelegate(list.NewDefaultDelegate())
+}
+
+// tableTitleView renders the list title, or the filter being typed, above
+// the column titles
+func tableTitleView(l list.Model, columns []tableColumn, order tableSort) string {
+	title := listTitleStyle.Render(l.Title)
+	if l.FilterState() == list.Filtering {
+		title = l.FilterInput.View()
+	}
+	return lipgloss.NewStyle().PaddingLeft(2).Render(title + "\n\n" + tableHeader(columns, order, l.Width()-2))
+}
@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+func TestLayoutColumns(t *testing.T) {
+	columns := []tableColumn{
+		{key: "name", keep: 9},
+		{key: "ip", width: 10, keep: 1},
+		{key: "status", width: 10, keep: 5},
+	}
+	// The marker, 12 for the flexible column, 10 and 10, and a gap after each
+	tests := []struct {
+		width int
+		want  []int
+	}{
+		{100, []int{70, 10, 10}},
+		{42, []int{12, 10, 10}},
+		{41, []int{23, 0, 10}},
+		{30, []int{12, 0, 10}},
+		{29, []int{23, 0, 0}},
+		{10, []int{12, 0, 0}},
+	}
+	for _, tt := range tests {
+		if got := layoutColumns(columns, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("layoutColumns(%d) = %v, want %v", tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestLayoutNodeColumnsFit(t *testing.T) {
+	for _, width := range []int{60, 80, 120, 200} {
+		widths := layoutColumns(nodeColumns, width)
+		used := markerWidth
+		for _, w := range widths {
+			if w > 0 {
+				used += w + 2
+			}
+		}
+		if used != width {
+			t.Errorf("node table at width %d takes %d", width, used)
+		}
+		if widths[0] == 0 {
+			t.Errorf("node table at width %d drops the name", width)
+		}
+	}
+}
+
+func nodeNamesOf(items []list.Item) string {
+	names := make([]string, len(items))
+	for i, it := range items {
+		names[i] = it.(nodeInfo).name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSortItems(t *testing.T) {
+	day := 24 * time.Hour
+	nodes := []list.Item{
+		nodeInfo{name: "c", age: 2 * day, version: "v1.29.10", podCount: 5},
+		nodeInfo{name: "a", age: 9 * day, version: "v1.29.9", podCount: -1},
+		nodeInfo{name: "d", age: 2 * day, version: "v1.28.15", podCount: 12},
+		nodeInfo{name: "b", age: 5 * day, version: "v1.29.9", podCount: 5},
+	}
+	tests := []struct {
+		order tableSort
+		want  string
+	}{
+		{defaultSort(SortByName), "a,b,c,d"},
+		{tableSort{column: SortByName, desc: true}, "d,c,b,a"},
+		// Ties fall back to the name, ascending either way
+		{defaultSort(SortByAge), "c,d,b,a"},
+		{tableSort{column: SortByAge, desc: true}, "a,b,c,d"},
+		// Versions compare by component, not as text
+		{defaultSort(SortByVersion), "d,a,b,c"},
+		// Unknown counts sort first
+		{defaultSort(SortByPods), "a,b,c,d"},
+		{tableSort{column: SortByPods, desc: true}, "d,b,c,a"},
+		{tableSort{column: "unknown"}, "c,a,d,b"},
+	}
+	for _, tt := range tests {
+		items := append([]list.Item(nil), nodes...)
+		sortItems(items, nodeColumns, tt.order)
+		if got := nodeNamesOf(items); got != tt.want {
+			t.Errorf("sort by %+v = %s, want %s", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestSortByColumn(t *testing.T) {
+	order := defaultSort(SortByName)
+	if got := sortByColumn(nodeColumns, order, 0); got != (tableSort{column: SortByName, desc: true}) {
+		t.Errorf("sorting by the sorted column again = %+v, want it reversed", got)
+	}
+	if got := sortByColumn(nodeColumns, order, 3); got != defaultSort(SortByAge) {
+		t.Errorf("sorting by column 4 = %+v, want %+v", got, defaultSort(SortByAge))
+	}
+	if got := sortByColumn(nodeColumns, order, len(nodeColumns)); got != order {
+		t.Errorf("sorting by a missing column = %+v, want the order kept", got)
+	}
+}
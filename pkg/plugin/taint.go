@@ -0,0 +1,216 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+// maintenanceTaintAnnotation remembers the taint the plugin put on a node, so
+// that uncordoning removes it whatever taint is configured at that time
+const maintenanceTaintAnnotation = "node-maintain.futuretea.io/maintenance-taint"
+
+// Maintenance modes offered before a destructive action
+const (
+	ModeCordon         = "cordon"
+	ModeTaint          = "taint"
+	ModeCordonAndTaint = "cordon+taint"
+)
+
+// DefaultMaintenanceTaint is the taint applied unless configured
+func DefaultMaintenanceTaint() corev1.Taint {
+	return corev1.Taint{Key: "node-maintain.futuretea.io/maintenance", Effect: corev1.TaintEffectNoSchedule}
+}
+
+// ParseTaint parses a taint in the kubectl taint form key[=value]:effect.
+// Only NoSchedule and NoExecute keep pods off the node, so PreferNoSchedule
+// is rejected.
+func ParseTaint(spec string) (corev1.Taint, error) {
+	i := strings.LastIndex(spec, ":")
+	if i < 0 {
+		return corev1.Taint{}, fmt.Errorf("invalid taint %q, must be key[=value]:effect", spec)
+	}
+	taint := corev1.Taint{Effect: corev1.TaintEffect(spec[i+1:])}
+	if taint.Effect != corev1.TaintEffectNoSchedule && taint.Effect != corev1.TaintEffectNoExecute {
+		return corev1.Taint{}, fmt.Errorf("invalid taint effect %q, must be %s or %s", taint.Effect, corev1.TaintEffectNoSchedule, corev1.TaintEffectNoExecute)
+	}
+	taint.Key, taint.Value, _ = strings.Cut(spec[:i], "=")
+	if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+		return corev1.Taint{}, fmt.Errorf("invalid taint key %q: %s", taint.Key, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsValidLabelValue(taint.Value); len(errs) > 0 {
+		return corev1.Taint{}, fmt.Errorf("invalid taint value %q: %s", taint.Value, strings.Join(errs, "; "))
+	}
+	return taint, nil
+}
+
+// maintenanceTaintOf returns the taint the plugin put on the node, if any
+func maintenanceTaintOf(node *corev1.Node) *corev1.Taint {
+	value, ok := node.Annotations[maintenanceTaintAnnotation]
+	if !ok {
+		return nil
+	}
+	taint, err := ParseTaint(value)
+	if err != nil {
+		return nil
+	}
+	return &taint
+}
+
+// hasTaint reports whether the node carries a taint with the key and effect
+func hasTaint(node *corev1.Node, taint *corev1.Taint) bool {
+	for i := range node.Spec.Taints {
+		if node.Spec.Taints[i].MatchTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// inMaintenance reports whether the node is cordoned or carries the
+// maintenance taint
+func inMaintenance(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return true
+	}
+	taint := maintenanceTaintOf(node)
+	return taint != nil && hasTaint(node, taint)
+}
+
+// taintNode adds the maintenance taint to the node
+func taintNode(drainer *drain.Helper, node *corev1.Node, taint corev1.Taint) NodeResult {
+	result := NodeResult{Node: node.Name, Action: MsgTaint}
+	fresh, err := drainer.Client.CoreV1().Nodes().Get(drainer.Ctx, node.Name, metav1.GetOptions{})
+	if err != nil {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("failed to get node: %v", err)
+		return result
+	}
+	// node is checked as well, it has the taint of an earlier dry run
+	if hasTaint(node, &taint) || hasTaint(fresh, &taint) {
+		result.Status = ResultUnchanged
+		result.Message = fmt.Sprintf("already tainted with %s", taint.Key)
+		return result
+	}
+
+	// Like kubectl taint, NoExecute taints record when they were added
+	if taint.Effect == corev1.TaintEffectNoExecute {
+		now := metav1.Now()
+		taint.TimeAdded = &now
+	}
+	taints := append(append([]corev1.Taint(nil), fresh.Spec.Taints...), taint)
+	change, err := patchTaints(drainer, node.Name, MsgTaint, taints, taint.ToString())
+	result.Changes = []Change{change}
+	if err != nil {
+		result.Status = ResultFailed
+		result.Message = err.Error()
+		return recordDone(drainer, result)
+	}
+	setMaintenanceTaint(node, taints, &taint)
+	result.Status = ResultSucceeded
+	result.Message = fmt.Sprintf("tainted with %s", taint.ToString())
+	if drainer.DryRunStrategy != cmdutil.DryRunNone {
+		result.Message = fmt.Sprintf("would be tainted with %s%s", taint.ToString(), dryRunSuffix(drainer.DryRunStrategy))
+	}
+	return recordDone(drainer, result)
+}
+
+// removeMaintenanceTaint removes the taint the plugin put on the node as part
+// of an uncordon, adding the change to result
+func removeMaintenanceTaint(drainer *drain.Helper, node *corev1.Node, result NodeResult) NodeResult {
+	taint := maintenanceTaintOf(node)
+	if taint == nil {
+		return result
+	}
+	fresh, err := drainer.Client.CoreV1().Nodes().Get(drainer.Ctx, node.Name, metav1.GetOptions{})
+	if err != nil {
+		result.Status = ResultFailed
+		result.Message = fmt.Sprintf("failed to get node: %v", err)
+		return result
+	}
+	var taints []corev1.Taint
+	for _, t := range fresh.Spec.Taints {
+		if !t.MatchTaint(taint) {
+			taints = append(taints, t)
+		}
+	}
+
+	change, err := patchTaints(drainer, node.Name, MsgUntaint, taints, nil)
+	result.Changes = append(result.Changes, change)
+	if err != nil {
+		result.Status = ResultFailed
+		result.Message = err.Error()
+		return result
+	}
+	setMaintenanceTaint(node, taints, nil)
+	message := fmt.Sprintf("removed taint %s", taint.ToString())
+	if drainer.DryRunStrategy != cmdutil.DryRunNone {
+		message = fmt.Sprintf("would remove taint %s%s", taint.ToString(), dryRunSuffix(drainer.DryRunStrategy))
+	}
+	if result.Status == ResultUnchanged {
+		result.Status = ResultSucceeded
+		result.Message = message
+	} else {
+		result.Message += ", " + message
+	}
+	return result
+}
+
+// patchTaints replaces the taints of the node and sets the maintenance taint
+// annotation, or removes it for nil, honouring the dry run of the drainer
+func patchTaints(drainer *drain.Helper, nodeName, action string, taints []corev1.Taint, annotation interface{}) (Change, error) {
+	change := Change{Action: action, Object: "node/" + nodeName}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{maintenanceTaintAnnotation: annotation},
+		},
+		"spec": map[string]interface{}{"taints": taints},
+	})
+	if err != nil {
+		change.Error = err.Error()
+		return change, fmt.Errorf("failed to build patch: %v", err)
+	}
+	change.Detail = "patch " + string(patch)
+	if drainer.DryRunStrategy == cmdutil.DryRunClient {
+		return change, nil
+	}
+
+	opts := metav1.PatchOptions{}
+	if drainer.DryRunStrategy == cmdutil.DryRunServer {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	if _, err := drainer.Client.CoreV1().Nodes().Patch(drainer.Ctx, nodeName, types.MergePatchType, patch, opts); err != nil {
+		change.Error = err.Error()
+		return change, fmt.Errorf("failed to %s node %s: %v", action, nodeName, err)
+	}
+	return change, nil
+}
+
+// setMaintenanceTaint mirrors a taint patch on node, the way
+// drain.RunCordonOrUncordon updates the node it is given
+func setMaintenanceTaint(node *corev1.Node, taints []corev1.Taint, taint *corev1.Taint) {
+	node.Spec.Taints = taints
+	if taint == nil {
+		delete(node.Annotations, maintenanceTaintAnnotation)
+		return
+	}
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[maintenanceTaintAnnotation] = taint.ToString()
+}
+
+// recordDone records the result of a change made for real
+func recordDone(drainer *drain.Helper, result NodeResult) NodeResult {
+	if drainer.DryRunStrategy != cmdutil.DryRunNone {
+		return result
+	}
+	return recordResult(drainer.Ctx, result, auditOptions{})
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseTaint(t *testing.T) {
+	tests := []struct {
+		spec  string
+		taint corev1.Taint
+	}{
+		{"maintenance:NoSchedule", corev1.Taint{Key: "maintenance", Effect: corev1.TaintEffectNoSchedule}},
+		{"maintenance=true:NoExecute", corev1.Taint{Key: "maintenance", Value: "true", Effect: corev1.TaintEffectNoExecute}},
+		{"example.com/maintenance=kernel-upgrade:NoSchedule", corev1.Taint{Key: "example.com/maintenance", Value: "kernel-upgrade", Effect: corev1.TaintEffectNoSchedule}},
+		{"maintenance=:NoSchedule", corev1.Taint{Key: "maintenance", Effect: corev1.TaintEffectNoSchedule}},
+	}
+	for _, tt := range tests {
+		taint, err := ParseTaint(tt.spec)
+		if err != nil {
+			t.Errorf("ParseTaint(%q): %v", tt.spec, err)
+			continue
+		}
+		if taint != tt.taint {
+			t.Errorf("ParseTaint(%q) = %+v, want %+v", tt.spec, taint, tt.taint)
+		}
+	}
+}
+
+func TestParseTaintErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"maintenance",
+		"maintenance=true",
+		"maintenance:PreferNoSchedule",
+		"maintenance:noschedule",
+		":NoSchedule",
+		"bad key:NoSchedule",
+		"maintenance=not valid:NoSchedule",
+	} {
+		if taint, err := ParseTaint(spec); err == nil {
+			t.Errorf("ParseTaint(%q) = %+v, want an error", spec, taint)
+		}
+	}
+}
+
+func TestInMaintenance(t *testing.T) {
+	taint := corev1.Taint{Key: "maintenance", Effect: corev1.TaintEffectNoSchedule}
+	annotated := metav1.ObjectMeta{Annotations: map[string]string{maintenanceTaintAnnotation: "maintenance:NoSchedule"}}
+	tests := []struct {
+		name string
+		node corev1.Node
+		want bool
+	}{
+		{"schedulable", corev1.Node{}, false},
+		{"cordoned", corev1.Node{Spec: corev1.NodeSpec{Unschedulable: true}}, true},
+		{"tainted", corev1.Node{ObjectMeta: annotated, Spec: corev1.NodeSpec{Taints: []corev1.Taint{taint}}}, true},
+		{"taint removed", corev1.Node{ObjectMeta: annotated}, false},
+		{"foreign taint", corev1.Node{Spec: corev1.NodeSpec{Taints: []corev1.Taint{taint}}}, false},
+	}
+	for _, tt := range tests {
+		if got := inMaintenance(&tt.node); got != tt.want {
+			t.Errorf("%s: inMaintenance = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,19 +1,69 @@
 package plugin
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 )
 
 // Message types
 type nodesMsg []nodeInfo
-type podsMsg []podInfo
+type podsMsg struct {
+	node string
+	pods []podInfo
+}
+type resultsMsg []NodeResult
+
+type preflightMsg struct {
+	node string
+	pdbs []pdbInfo
+	err  error
+}
+
+type impactMsg struct {
+	node   string
+	report impactReport
+	err    error
+}
+
+type blastMsg struct {
+	node      string
+	workloads []workloadInfo
+	err       error
+}
+
+// podCountsMsg carries the pod count of every node, err is set when the pods
+// could not be listed
+type podCountsMsg struct {
+	counts map[string]int
+	err    error
+}
+
+// finishedPodsMsg carries the finished pods of the cluster by node
+type finishedPodsMsg struct {
+	pods map[string][]podInfo
+	err  error
+}
+
+type nodeDetailMsg struct {
+	node   string
+	detail nodeDetail
+	err    error
+}
+
+// interruptMsg is sent when the process receives SIGINT or SIGTERM
+type interruptMsg struct{}
 
 // UI item types
 type item struct {
@@ -24,20 +74,54 @@ func (i item) Title() string       { return i.title }
 func (i item) Description() string { return i.desc }
 func (i item) FilterValue() string { return i.title }
 
+// drainOptionItem is a list entry for a single drain option
+type drainOptionItem struct {
+	key, label, value, desc string
+}
+
+func (i drainOptionItem) Title() string       { return fmt.Sprintf("%s: %s", i.label, i.value) }
+func (i drainOptionItem) Description() string { return i.desc }
+func (i drainOptionItem) FilterValue() string { return i.label }
+
 const (
-	StateSelectNode    = "selectNode"
-	StateSelectAction  = "selectAction"
-	StateConfirmCordon = "confirmCordon"
-	StateConfirmToggle = "confirmToggle"
-	StateConfirm       = "confirm"
-	StateSelectPods    = "selectPods"
-	StateConfirmPod    = "confirmPod"
+	StateSelectNode       = "selectNode"
+	StateSelectAction     = "selectAction"
+	StateConfirmCordon    = "confirmCordon"
+	StateConfirmToggle    = "confirmToggle"
+	StateConfirm          = "confirm"
+	StateSelectPods       = "selectPods"
+	StateConfirmPod       = "confirmPod"
+	StateDrainOptions     = "drainOptions"
+	StateEditOption       = "editOption"
+	StateReport           = "report"
+	StateProgress         = "progress"
+	StatePreflight        = "preflight"
+	StateImpact           = "impact"
+	StateSelectBulkAction = "selectBulkAction"
+	StateConfirmBulk      = "confirmBulk"
+	StateResume           = "resume"
+	StateCordonReason     = "cordonReason"
+	StateNodeDetail       = "nodeDetail"
 
 	// Actions
 	ActionForceDrainNode      = "Force Drain node"
 	ActionForceDeleteNonDS    = "Force delete non-daemonset pods"
 	ActionForceDeleteSelected = "Force delete selected pods"
+	ActionCleanupFinished     = "Clean up finished pods"
+	ActionAnalyzeImpact       = "Analyze impact"
+	ActionBulkCordon          = "Cordon selected nodes"
+	ActionBulkUncordon        = "Uncordon selected nodes"
+	ActionBulkDrain           = "Drain selected nodes"
+	ActionBulkRollout         = "Rolling maintenance of selected nodes"
+	ActionBulkCleanup         = "Clean up finished pods on selected nodes"
+	ActionClusterCleanup      = "Clean up finished pods on all nodes"
+	ActionTaint               = "Taint instead"
+	ActionCordonAndTaint      = "Cordon and taint"
+	ActionResume              = "Resume"
+	ActionResumeLater         = "Later"
+	ActionDiscard             = "Discard"
 	ActionBack                = "Back"
+	ActionContinue            = "Continue"
 
 	// Confirmations
 	ConfirmYes = "Yes"
@@ -47,12 +131,28 @@ const (
 	DescDrainNode           = "Execute drain operation"
 	DescForceDeleteNonDS    = "Delete all non-DaemonSet pods"
 	DescForceDeleteSelected = "Choose pods to delete"
+	DescCleanupFinished     = "Delete Failed, Completed and Evicted pods, the node is not cordoned"
+	DescAnalyzeImpact       = "Check whether the other nodes can absorb this node's pods"
+	DescBulkCordon          = "Mark every selected node unschedulable"
+	DescBulkUncordon        = "Mark every selected node schedulable again"
+	DescBulkDrain           = "Cordon and drain the selected nodes one after another"
+	DescBulkRollout         = "Drain, maintain and uncordon the selected nodes a few at a time"
+	DescBulkCleanup         = "Delete Failed, Completed and Evicted pods on the selected nodes, none is cordoned"
+	DescResumeLater         = "Keep the operations for a later resume"
+	DescDiscard             = "Forget the operations, the nodes stay as they are"
 	DescCancelBack          = "Cancel and go back"
 	DescBack                = "Return to previous screen"
+	DescContinue            = "Proceed with these drain options"
+	DescContinueDelete      = "Proceed with these delete options"
+	DescPreflightContinue   = "Proceed to cordon and drain the node"
 
 	// Messages
 	MsgCordon   = "cordon"
 	MsgUncordon = "uncordon"
+	MsgDrain    = "drain"
+	MsgRollout  = "rollout"
+	MsgTaint    = "taint"
+	MsgUntaint  = "untaint"
 )
 
 type model struct {
@@ -64,12 +164,44 @@ type model struct {
 	selectedNode     *corev1.Node
 	pods             []podInfo
 	selectedPods     map[string]podInfo // key: namespace/name
+	selectedNodes    map[string]nodeInfo
 	state            State
 	err              error
 	clientset        *kubernetes.Clientset
 	quitting         bool
 	confirm          bool
 	action           string
+	drainSettings    DrainSettings
+	deleteSettings   DeleteSettings
+	rolloutSettings  RolloutSettings
+	input            textinput.Model
+	editingOption    string
+	dryRun           cmdutil.DryRunStrategy
+	results          []NodeResult
+	report           viewport.Model
+	watcher          *watcher
+	progress         progressView
+	notice           string
+	analyzing        bool
+	ctx              context.Context
+	control          *control
+	gate             *keyGate
+	quitAfterOp      bool
+	resumeOffered    bool
+	unfinished       []OperationState
+	operator         *operator
+	maintenanceTaint corev1.Taint
+	maintenanceMode  string
+	cordonNote       *CordonNote // reason being entered or given for the cordon
+	reasonFor        State       // confirmation the reason prompt continues
+	nodeUsage        map[string]resourceUsage
+	podUsage         map[string]resourceUsage
+	nodeSort         tableSort
+	podSort          tableSort
+	tableView        bool
+	podCounts        map[string]int
+	nodeFilter       NodeFilter
+	nodeIndex        *nodeIndex
 }
 
 // Constants for key bindings
@@ -80,6 +212,22 @@ const (
 	KeyQ     = "q"
 	KeyEsc   = "esc"
 	KeyC     = "c"
+	KeyP     = "p"
+	KeyX     = "x"
+	KeyA     = "a"
+	KeyR     = "r"
+	KeyD     = "d"
+	KeyS     = "s"
+	KeyT     = "t"
+	KeyN     = "n"
+	KeyI     = "i"
+	KeyV     = "v"
+
+	// Pod picker keys selecting the pods that share a field with the pod
+	// under the cursor
+	KeySameNamespace = "N"
+	KeySameOwner     = "o"
+	KeySamePhase     = "P"
 )
 
 type nodeInfo struct {
@@ -91,6 +239,16 @@ type nodeInfo struct {
 	version     string
 	internal    string
 	conditions  []string
+	selected    bool
+	operation   *OperationState
+	cordon      *CordonNote
+	taint       string // maintenance taint on the node, if any
+	labels      map[string]string
+
+	allocatableCPU    resource.Quantity
+	allocatableMemory resource.Quantity
+	usage             *resourceUsage // nil without metrics
+	podCount          int            // pods not terminated, -1 when unknown
 }
 
 func (n nodeInfo) Title() string {
@@ -98,24 +256,72 @@ func (n nodeInfo) Title() string {
 	if !n.schedulable {
 		status = "Cordoned"
 	}
+	if n.taint != "" {
+		status += ", Tainted " + n.taint
+	}
+	if n.selected {
+		return fmt.Sprintf("[✓] %s (%s)", n.name, status)
+	}
 	return fmt.Sprintf("%s (%s)", n.name, status)
 }
 
 func (n nodeInfo) Description() string {
-	return fmt.Sprintf("Status: %s | Roles: %s | Age: %s | Version: %s | InternalIP: %s | Conditions: %s",
+	desc := fmt.Sprintf("Status: %s | Roles: %s | Age: %s | Version: %s | InternalIP: %s | Conditions: %s",
 		n.status,
 		strings.Join(n.roles, ","),
 		formatDuration(n.age),
 		n.version,
-		n.internal,
+		valueOrNone(n.internal),
 		strings.Join(n.conditions, ","),
 	)
+	if n.usage != nil {
+		desc += fmt.Sprintf(" | CPU: %s | Memory: %s",
+			usageText(formatCPU(n.usage.cpu), n.usagePercent(SortByCPU)),
+			usageText(formatMemory(n.usage.memory), n.usagePercent(SortByMemory)))
+	}
+	if n.cordon != nil && !n.schedulable {
+		desc += fmt.Sprintf(" | Cordon reason: %s", n.cordon)
+	}
+	if n.operation != nil {
+		desc += fmt.Sprintf(" | Unfinished %s: %s", n.operation.Action, n.operation.summary())
+	}
+	return desc
 }
 
 func (n nodeInfo) FilterValue() string {
 	return n.name
 }
 
+// statusText is the readiness with the maintenance state, for the table view
+func (n nodeInfo) statusText() string {
+	status := n.status
+	if !n.schedulable {
+		status += ",Cordoned"
+	}
+	if n.taint != "" {
+		status += ",Tainted"
+	}
+	return status
+}
+
+func (n nodeInfo) podCountText() string {
+	if n.podCount < 0 {
+		return "?"
+	}
+	return fmt.Sprintf("%d", n.podCount)
+}
+
+// usageCell renders the CPU or memory usage for the table view
+func (n nodeInfo) usageCell(resourceName string) string {
+	if n.usage == nil {
+		return "-"
+	}
+	if resourceName == SortByCPU {
+		return usageText(formatCPU(n.usage.cpu), n.usagePercent(SortByCPU))
+	}
+	return usageText(formatMemory(n.usage.memory), n.usagePercent(SortByMemory))
+}
+
 func formatDuration(d time.Duration) string {
 	d = d.Round(time.Minute)
 	h := d / time.Hour
@@ -135,11 +341,13 @@ func formatDuration(d time.Duration) string {
 type podInfo struct {
 	name      string
 	namespace string
+	uid       types.UID
 	owner     string
 	ownerKind string
 	phase     string
 	age       time.Duration
 	selected  bool
+	usage     *resourceUsage // nil without metrics
 }
 
 func (p podInfo) Title() string {
@@ -155,17 +363,163 @@ func (p podInfo) Description() string {
 	if owner == "" {
 		owner = "<none>"
 	}
-	return fmt.Sprintf("Namespace: %s | Phase: %s | Owner: %s(%s) | Age: %s",
+	desc := fmt.Sprintf("Namespace: %s | Phase: %s | Owner: %s(%s) | Age: %s",
 		p.namespace,
 		p.phase,
 		owner,
 		p.ownerKind,
 		formatDuration(p.age),
 	)
+	if p.usage != nil {
+		desc += fmt.Sprintf(" | CPU: %s | Memory: %s", formatCPU(p.usage.cpu), formatMemory(p.usage.memory))
+	}
+	return desc
 }
 
 func (p podInfo) FilterValue() string {
+	return p.key()
+}
+
+// key identifies the pod in model.selectedPods
+func (p podInfo) key() string {
 	return p.namespace + "/" + p.name
 }
 
+func (p podInfo) ownerText() string {
+	if p.owner == "" {
+		return "<none>"
+	}
+	return p.ownerKind + "/" + p.owner
+}
+
+// usageCell renders the CPU or memory usage for the table view
+func (p podInfo) usageCell(resourceName string) string {
+	if p.usage == nil {
+		return "-"
+	}
+	if resourceName == SortByCPU {
+		return formatCPU(p.usage.cpu)
+	}
+	return formatMemory(p.usage.memory)
+}
+
+// usageValue returns the CPU in millicores or the memory in bytes, -1 when
+// unknown
+func (p podInfo) usageValue(resourceName string) int64 {
+	if p.usage == nil {
+		return -1
+	}
+	if resourceName == SortByCPU {
+		return p.usage.cpu.MilliValue()
+	}
+	return p.usage.memory.Value()
+}
+
 type State string
+
+// pdbInfo describes a PodDisruptionBudget covering pods on the selected node
+type pdbInfo struct {
+	name               string
+	namespace          string
+	currentHealthy     int32
+	desiredHealthy     int32
+	expectedPods       int32
+	disruptionsAllowed int32
+	pods               []string // covered pods on the node
+}
+
+// blocked returns how many of the covered pods cannot be evicted right away
+func (p pdbInfo) blocked() int {
+	if n := len(p.pods) - int(p.disruptionsAllowed); n > 0 {
+		return n
+	}
+	return 0
+}
+
+func (p pdbInfo) Title() string {
+	status := "OK"
+	if blocked := p.blocked(); blocked == len(p.pods) {
+		status = fmt.Sprintf("BLOCKS all %d pods", blocked)
+	} else if blocked > 0 {
+		status = fmt.Sprintf("BLOCKS %d of %d pods", blocked, len(p.pods))
+	}
+	return fmt.Sprintf("%s/%s (%s)", p.namespace, p.name, status)
+}
+
+func (p pdbInfo) Description() string {
+	return fmt.Sprintf("Healthy: %d/%d | Desired: %d | Disruptions allowed: %d | Pods: %s",
+		p.currentHealthy,
+		p.expectedPods,
+		p.desiredHealthy,
+		p.disruptionsAllowed,
+		strings.Join(p.pods, ","),
+	)
+}
+
+func (p pdbInfo) FilterValue() string {
+	return p.namespace + "/" + p.name
+}
+
+// podPlacement records where the drain simulation put a pod; node is empty
+// when no node fits and the pod would stay Pending
+type podPlacement struct {
+	pod    string // namespace/name
+	node   string
+	reason string
+}
+
+// impactReport is the outcome of simulating a drain of one node
+type impactReport struct {
+	node       string
+	candidates int
+	placements []podPlacement
+	unmanaged  []string // pods without a controller, gone for good
+}
+
+// pending returns the number of pods no node could take
+func (r impactReport) pending() int {
+	pending := 0
+	for _, p := range r.placements {
+		if p.node == "" {
+			pending++
+		}
+	}
+	return pending
+}
+
+// workloadInfo groups the affected pods on a node by top-level workload
+type workloadInfo struct {
+	kind        string
+	namespace   string
+	name        string
+	pods        []string
+	readyOnNode int
+	readyTotal  int   // -1 when unknown
+	replicas    int32 // -1 when unknown
+	warning     string
+}
+
+func (w workloadInfo) Title() string {
+	title := fmt.Sprintf("%s %s/%s", w.kind, w.namespace, w.name)
+	if w.warning != "" {
+		title = "⚠ " + title + ": " + w.warning
+	}
+	return title
+}
+
+func (w workloadInfo) Description() string {
+	replicas := "n/a"
+	if w.replicas >= 0 {
+		replicas = fmt.Sprintf("%d ready of %d desired", w.readyTotal, w.replicas)
+	}
+	return fmt.Sprintf("On node: %d pods (%d ready) | Replicas: %s | Pods: %s",
+		len(w.pods),
+		w.readyOnNode,
+		replicas,
+		strings.Join(w.pods, ","),
+	)
+}
+
+func (w workloadInfo) FilterValue() string {
+	return w.namespace + "/" + w.name
+}
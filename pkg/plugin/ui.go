@@ -1,19 +1,301 @@
 package plugin
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 )
 
+// listTitleStyle is the style of list titles
+var listTitleStyle = lipgloss.NewStyle().
+	Background(lipgloss.Color("62")).
+	Foreground(lipgloss.Color("230")).
+	Padding(0, 1)
+
 func createList(items []list.Item, title string, width, height int) list.Model {
 	l := list.New(items, list.NewDefaultDelegate(), width, height)
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(true)
 	l.Title = title
-	titleStyle := lipgloss.NewStyle().
-		Background(lipgloss.Color("62")).
-		Foreground(lipgloss.Color("230")).
-		Padding(0, 1)
-	l.Styles.Title = titleStyle
+	l.Styles.Title = listTitleStyle
 	return l
 }
+
+// actionItems returns the operations offered for a selected node
+func actionItems() []list.Item {
+	return []list.Item{
+		item{title: ActionForceDrainNode, desc: DescDrainNode},
+		item{title: ActionForceDeleteNonDS, desc: DescForceDeleteNonDS},
+		item{title: ActionForceDeleteSelected, desc: DescForceDeleteSelected},
+		item{title: ActionCleanupFinished, desc: DescCleanupFinished},
+		item{title: ActionAnalyzeImpact, desc: DescAnalyzeImpact},
+		item{title: ActionBack, desc: DescBack},
+	}
+}
+
+// bulkActionItems returns the operations offered for several selected nodes
+func bulkActionItems() []list.Item {
+	return []list.Item{
+		item{title: ActionBulkCordon, desc: DescBulkCordon},
+		item{title: ActionBulkUncordon, desc: DescBulkUncordon},
+		item{title: ActionBulkDrain, desc: DescBulkDrain},
+		item{title: ActionBulkRollout, desc: DescBulkRollout},
+		item{title: ActionBulkCleanup, desc: DescBulkCleanup},
+		item{title: ActionBack, desc: DescBack},
+	}
+}
+
+// resumeItems offers to resume the unfinished operations found on startup,
+// followed by the operations themselves
+func resumeItems(ops []OperationState) []list.Item {
+	items := []list.Item{
+		item{title: ActionResume, desc: fmt.Sprintf("Pick up %d unfinished operations where they stopped", len(ops))},
+		item{title: ActionResumeLater, desc: DescResumeLater},
+		item{title: ActionDiscard, desc: DescDiscard},
+	}
+	for _, op := range ops {
+		items = append(items, op)
+	}
+	return items
+}
+
+// isDeleteAction reports whether the action deletes pods on the selected node
+func isDeleteAction(action string) bool {
+	return action == ActionForceDeleteNonDS || action == ActionForceDeleteSelected
+}
+
+// isBulkAction reports whether the action runs on the selected nodes, or on
+// all nodes
+func isBulkAction(action string) bool {
+	switch action {
+	case ActionBulkCordon, ActionBulkUncordon, ActionBulkDrain, ActionBulkRollout, ActionBulkCleanup, ActionClusterCleanup:
+		return true
+	}
+	return false
+}
+
+// drainOptionItems renders the drain settings as selectable list entries
+func drainOptionItems(s DrainSettings) []list.Item {
+	gracePeriod := fmt.Sprintf("%ds", s.GracePeriodSeconds)
+	if s.GracePeriodSeconds < 0 {
+		gracePeriod = "pod default"
+	}
+	timeout := s.Timeout.String()
+	if s.Timeout == 0 {
+		timeout = "infinite"
+	}
+	podSelector := s.PodSelector
+	if podSelector == "" {
+		podSelector = "<all pods>"
+	}
+	skipWait := fmt.Sprintf("%ds", s.SkipWaitForDeleteTimeoutSeconds)
+	if s.SkipWaitForDeleteTimeoutSeconds == 0 {
+		skipWait = "disabled"
+	}
+
+	return []list.Item{
+		drainOptionItem{key: optionForce, label: "Force", value: fmt.Sprint(s.Force),
+			desc: "Continue even if there are pods not managed by a controller"},
+		drainOptionItem{key: optionGracePeriod, label: "Grace period", value: gracePeriod,
+			desc: "Time given to each pod to terminate gracefully"},
+		drainOptionItem{key: optionTimeout, label: "Timeout", value: timeout,
+			desc: "How long to wait before giving up on the drain"},
+		drainOptionItem{key: optionDeleteEmptyDir, label: "Delete emptyDir data", value: fmt.Sprint(s.DeleteEmptyDirData),
+			desc: "Continue even if pods use emptyDir volumes"},
+		drainOptionItem{key: optionIgnoreDaemonSets, label: "Ignore DaemonSets", value: fmt.Sprint(s.IgnoreAllDaemonSets),
+			desc: "Skip DaemonSet-managed pods"},
+		drainOptionItem{key: optionPodSelector, label: "Pod selector", value: podSelector,
+			desc: "Only drain pods matching this label selector"},
+		drainOptionItem{key: optionSkipWaitForDelete, label: "Skip wait for delete timeout", value: skipWait,
+			desc: "Stop waiting for pods whose deletion started more than this long ago"},
+		drainOptionItem{key: optionDisableEviction, label: "Disable eviction", value: fmt.Sprint(s.DisableEviction),
+			desc: "Delete pods instead of evicting them, bypassing PodDisruptionBudgets"},
+		item{title: ActionContinue, desc: DescContinue},
+		item{title: ActionBack, desc: DescBack},
+	}
+}
+
+// deleteOptionItems renders the delete settings as selectable list entries
+func deleteOptionItems(s DeleteSettings) []list.Item {
+	return []list.Item{
+		drainOptionItem{key: optionDeleteMethod, label: "Method", value: s.Method,
+			desc: deleteMethodDescription(s.Method)},
+		drainOptionItem{key: optionDeleteTimeout, label: "Eviction timeout", value: s.Timeout.String(),
+			desc: "How long a blocked eviction is retried; evict-then-force force deletes pods not gone by then"},
+		item{title: ActionContinue, desc: DescContinueDelete},
+		item{title: ActionBack, desc: DescBack},
+	}
+}
+
+// deleteMethodDescription explains a delete method
+func deleteMethodDescription(method string) string {
+	switch method {
+	case DeleteEvict:
+		return "Evict pods through the Eviction API, respecting PodDisruptionBudgets"
+	case DeleteGraceful:
+		return "Delete pods with their own grace period, bypassing PodDisruptionBudgets"
+	case DeleteEvictThenForce:
+		return "Evict pods, then force delete those not gone by the timeout"
+	}
+	return "Delete pods with a zero grace period, skipping graceful shutdown and PodDisruptionBudgets"
+}
+
+// rolloutOptionItems puts the rollout settings in front of the drain options
+func rolloutOptionItems(s DrainSettings, r RolloutSettings) []list.Item {
+	items := []list.Item{
+		drainOptionItem{key: optionMaxUnavailable, label: "Max unavailable", value: fmt.Sprintf("%d nodes", r.MaxUnavailable),
+			desc: "Nodes taken out of service at the same time"},
+	}
+	return append(items, drainOptionItems(s)...)
+}
+
+// preflightItems lists the PodDisruptionBudgets found for a drain, followed by
+// the choice to go on or back
+func preflightItems(msg preflightMsg, disableEviction bool) []list.Item {
+	var items []list.Item
+	switch {
+	case msg.err != nil:
+		items = append(items, item{title: "Could not check PodDisruptionBudgets", desc: msg.err.Error()})
+	case len(msg.pdbs) == 0:
+		items = append(items, item{title: "No PodDisruptionBudgets", desc: "No budget covers the pods this drain would evict"})
+	}
+	for _, pdb := range msg.pdbs {
+		items = append(items, pdb)
+	}
+
+	desc := DescPreflightContinue
+	if disableEviction {
+		desc += " (eviction is disabled, budgets are bypassed)"
+	}
+	return append(items,
+		item{title: ActionContinue, desc: desc},
+		item{title: ActionBack, desc: DescBack},
+	)
+}
+
+// preflightTitle summarizes how many pods the budgets will hold up
+func preflightTitle(pdbs []pdbInfo) string {
+	blocked := 0
+	for _, pdb := range pdbs {
+		blocked += pdb.blocked()
+	}
+	if blocked == 0 {
+		return "Disruption Budgets"
+	}
+	return fmt.Sprintf("Disruption Budgets: %d pods will block eviction", blocked)
+}
+
+// impactTitle renders the heading of the impact analysis screen
+func impactTitle(nodeName string) string {
+	return listTitleStyle.Render("Impact of draining " + nodeName)
+}
+
+// nodeDetailTitle renders the heading of the node detail screen
+func nodeDetailTitle(nodeName string) string {
+	return listTitleStyle.Render("Node " + nodeName)
+}
+
+// clusterCleanupItems lists the finished pods per node below the confirmation
+// of a cluster-wide cleanup, which is only offered when there are any
+func clusterCleanupItems(msg finishedPodsMsg) []list.Item {
+	back := item{title: ConfirmNo, desc: DescCancelBack}
+	if msg.err != nil {
+		return []list.Item{item{title: "Could not list finished pods", desc: msg.err.Error()}, back}
+	}
+	total := 0
+	names := make([]string, 0, len(msg.pods))
+	for name, pods := range msg.pods {
+		total += len(pods)
+		names = append(names, name)
+	}
+	if total == 0 {
+		return []list.Item{item{title: "No finished pods", desc: "No node has Failed, Completed or Evicted pods"}, back}
+	}
+	sort.Strings(names)
+
+	items := []list.Item{
+		item{title: ConfirmYes, desc: fmt.Sprintf("Confirm deleting %d Failed, Completed and Evicted pods on %d nodes", total, len(names))},
+		back,
+	}
+	for _, name := range names {
+		items = append(items, item{title: name, desc: fmt.Sprintf("%d finished pods", len(msg.pods[name]))})
+	}
+	return items
+}
+
+// blastRadiusItems lists the affected workloads below a confirmation
+func blastRadiusItems(msg blastMsg) []list.Item {
+	if msg.err != nil {
+		return []list.Item{item{title: "Could not resolve affected workloads", desc: msg.err.Error()}}
+	}
+	items := make([]list.Item, 0, len(msg.workloads))
+	for _, w := range msg.workloads {
+		items = append(items, w)
+	}
+	return items
+}
+
+// blastRadiusTitle adds the number of workload warnings to a confirmation title
+func blastRadiusTitle(title string, workloads []workloadInfo) string {
+	warnings := 0
+	for _, w := range workloads {
+		if w.warning != "" {
+			warnings++
+		}
+	}
+	if warnings == 0 {
+		return fmt.Sprintf("%s (%d workloads affected)", title, len(workloads))
+	}
+	return fmt.Sprintf("%s (%d workloads affected, %d warnings)", title, len(workloads), warnings)
+}
+
+// reportChrome is the number of lines around the report viewport
+const reportChrome = 4
+
+// reportTitle renders the heading of the report screen
+func reportTitle(dryRun cmdutil.DryRunStrategy) string {
+	title := "Operation Results"
+	if dryRun != cmdutil.DryRunNone {
+		title = "Dry-run Report" + dryRunSuffix(dryRun) + ": no changes were made"
+	}
+	return listTitleStyle.Render(title)
+}
+
+// formatReport renders per-node results followed by every recorded change
+func formatReport(results []NodeResult, dryRun cmdutil.DryRunStrategy) string {
+	var b strings.Builder
+	_ = PrintNodeResults(&b, results)
+	b.WriteString("\n")
+	if dryRun != cmdutil.DryRunNone {
+		b.WriteString("Planned changes:\n")
+	} else {
+		b.WriteString("Changes:\n")
+	}
+	_ = PrintChanges(&b, results)
+	return b.String()
+}
+
+// setItemsKeepCursor replaces the list items without resetting the filter and
+// keeps the cursor on the same entry when it still exists
+func setItemsKeepCursor(l *list.Model, items []list.Item) tea.Cmd {
+	var selected string
+	if current := l.SelectedItem(); current != nil {
+		selected = current.FilterValue()
+	}
+
+	cmd := l.SetItems(items)
+	if l.FilterState() == list.Unfiltered && selected != "" {
+		for i, it := range items {
+			if it.FilterValue() == selected {
+				l.Select(i)
+				break
+			}
+		}
+	}
+	return cmd
+}
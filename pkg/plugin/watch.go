@@ -0,0 +1,233 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// watchThrottle bounds how often a burst of informer events refreshes a list
+const watchThrottle = 250 * time.Millisecond
+
+// watcher keeps the node list, the pod list of the selected node and, while
+// they are shown, the pod counts of all nodes up to date through shared
+// informers. Snapshots are delivered to the running program as nodesMsg,
+// podsMsg and podCountsMsg.
+type watcher struct {
+	clientset *kubernetes.Clientset
+
+	mu        sync.Mutex
+	send      func(tea.Msg)
+	nodeStop  chan struct{}
+	podStop   chan struct{}
+	countStop chan struct{}
+}
+
+func newWatcher(clientset *kubernetes.Clientset) *watcher {
+	return &watcher{clientset: clientset}
+}
+
+// start begins watching nodes and delivers updates through send
+func (w *watcher) start(send func(tea.Msg)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.send = send
+	w.nodeStop = make(chan struct{})
+
+	factory := informers.NewSharedInformerFactory(w.clientset, 0)
+	lister := factory.Core().V1().Nodes().Lister()
+	informer := factory.Core().V1().Nodes().Informer()
+	w.run(factory, informer, w.nodeStop, func() tea.Msg {
+		nodes, err := lister.List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		return nodesSnapshot(nodes)
+	}, nil)
+}
+
+// watchPods switches the pod watch to nodeName, replacing any previous one
+func (w *watcher) watchPods(nodeName string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.send == nil {
+		return
+	}
+	if w.podStop != nil {
+		close(w.podStop)
+	}
+	w.podStop = make(chan struct{})
+
+	factory := informers.NewSharedInformerFactoryWithOptions(w.clientset, 0,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+		}))
+	lister := factory.Core().V1().Pods().Lister()
+	informer := factory.Core().V1().Pods().Informer()
+	w.run(factory, informer, w.podStop, func() tea.Msg {
+		pods, err := lister.List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		return podsSnapshot(nodeName, pods)
+	}, nil)
+}
+
+// watchPodCounts starts counting the pods of every node, unless it already
+// runs. Terminated pods are left out by the API server.
+func (w *watcher) watchPodCounts() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.send == nil || w.countStop != nil {
+		return
+	}
+	w.countStop = make(chan struct{})
+
+	factory := informers.NewSharedInformerFactoryWithOptions(w.clientset, 0,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = runningPodSelector
+		}))
+	lister := factory.Core().V1().Pods().Lister()
+	informer := factory.Core().V1().Pods().Informer()
+	w.run(factory, informer, w.countStop, func() tea.Msg {
+		pods, err := lister.List(labels.Everything())
+		if err != nil {
+			return podCountsMsg{err: err}
+		}
+		return podCountsMsg{counts: podCounts(pods)}
+	}, func(err error) tea.Msg {
+		return podCountsMsg{err: fmt.Errorf("failed to list pods: %v", err)}
+	})
+}
+
+// stopPodCounts ends the pod count watch
+func (w *watcher) stopPodCounts() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.countStop != nil {
+		close(w.countStop)
+		w.countStop = nil
+	}
+}
+
+// stopPods ends the pod watch
+func (w *watcher) stopPods() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.podStop != nil {
+		close(w.podStop)
+		w.podStop = nil
+	}
+}
+
+// stop ends all watches
+func (w *watcher) stop() {
+	w.stopPods()
+	w.stopPodCounts()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.nodeStop != nil {
+		close(w.nodeStop)
+		w.nodeStop = nil
+	}
+}
+
+// run starts the informer and sends a fresh snapshot after the initial sync
+// and after every burst of changes. Until the first sync, failed lists are
+// sent through failed when it is set.
+func (w *watcher) run(factory informers.SharedInformerFactory, informer cache.SharedIndexInformer, stop chan struct{}, snapshot func() tea.Msg, failed func(error) tea.Msg) {
+	send := w.send
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+
+	// The reflector retries failed watches on its own; the default handler
+	// would log to stderr underneath the alt screen
+	_ = informer.SetWatchErrorHandler(func(_ *cache.Reflector, err error) {
+		if failed != nil && !informer.HasSynced() {
+			send(failed(err))
+		}
+	})
+	_, _ = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { notify() },
+		UpdateFunc: func(interface{}, interface{}) { notify() },
+		DeleteFunc: func(interface{}) { notify() },
+	})
+	factory.Start(stop)
+
+	go func() {
+		if !cache.WaitForCacheSync(stop, informer.HasSynced) {
+			return
+		}
+		// The initial snapshot already covers the adds seen during sync
+		select {
+		case <-changed:
+		default:
+		}
+		send(snapshot())
+		for {
+			select {
+			case <-stop:
+				return
+			case <-changed:
+				send(snapshot())
+				// Coalesce the events that follow in quick succession
+				select {
+				case <-stop:
+					return
+				case <-time.After(watchThrottle):
+				}
+			}
+		}
+	}()
+}
+
+// nodesSnapshot converts informer nodes into a sorted nodesMsg
+func nodesSnapshot(nodes []*corev1.Node) nodesMsg {
+	infos := make([]nodeInfo, 0, len(nodes))
+	for _, node := range nodes {
+		infos = append(infos, newNodeInfo(*node))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].name < infos[j].name })
+	return nodesMsg(infos)
+}
+
+// podCounts returns the number of pods by node
+func podCounts(pods []*corev1.Pod) map[string]int {
+	counts := make(map[string]int)
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			counts[pod.Spec.NodeName]++
+		}
+	}
+	return counts
+}
+
+// podsSnapshot converts informer pods into a sorted podsMsg
+func podsSnapshot(nodeName string, pods []*corev1.Pod) podsMsg {
+	infos := make([]podInfo, 0, len(pods))
+	for _, pod := range pods {
+		infos = append(infos, newPodInfo(*pod))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].namespace != infos[j].namespace {
+			return infos[i].namespace < infos[j].namespace
+		}
+		return infos[i].name < infos[j].name
+	})
+	return podsMsg{node: nodeName, pods: infos}
+}
@@ -0,0 +1,210 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// checkBlastRadius resolves the workloads behind the pods an action would
+// remove from the node. Only pods for which include returns true count.
+func checkBlastRadius(clientset *kubernetes.Clientset, nodeName string, include func(corev1.Pod) bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		workloads, err := blastRadius(ctx, clientset, nodeName, include)
+		return blastMsg{node: nodeName, workloads: workloads, err: err}
+	}
+}
+
+// drainedPods matches the pods a drain with the given pod selector evicts
+func drainedPods(podSelector string) func(corev1.Pod) bool {
+	selector, err := labels.Parse(podSelector)
+	if err != nil {
+		selector = labels.Everything()
+	}
+	return func(pod corev1.Pod) bool {
+		return !isDaemonSetPod(pod) && selector.Matches(labels.Set(pod.Labels))
+	}
+}
+
+// selectedPodsFilter matches the pods picked in the TUI
+func selectedPodsFilter(selected map[string]podInfo) func(corev1.Pod) bool {
+	keys := make(map[string]bool, len(selected))
+	for key := range selected {
+		keys[key] = true
+	}
+	return func(pod corev1.Pod) bool {
+		return keys[pod.Namespace+"/"+pod.Name]
+	}
+}
+
+// blastRadius groups the affected pods on the node by their top-level
+// workload and flags the workloads that lose all their ready replicas, or a
+// StatefulSet that drops below quorum. Workloads with warnings come first.
+func blastRadius(ctx context.Context, clientset *kubernetes.Clientset, nodeName string, include func(corev1.Pod) bool) ([]workloadInfo, error) {
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods on node %s: %v", nodeName, err)
+	}
+
+	resolver := newOwnerResolver(ctx, clientset)
+	byWorkload := make(map[string]*workloadInfo)
+	var order []string
+	for _, pod := range pods.Items {
+		if !include(pod) {
+			continue
+		}
+		kind, name := resolver.topLevel(&pod)
+		key := kind + "/" + pod.Namespace + "/" + name
+		w, ok := byWorkload[key]
+		if !ok {
+			w = &workloadInfo{kind: kind, namespace: pod.Namespace, name: name}
+			byWorkload[key] = w
+			order = append(order, key)
+		}
+		w.pods = append(w.pods, pod.Name)
+		if isPodReady(&pod) {
+			w.readyOnNode++
+		}
+	}
+
+	workloads := make([]workloadInfo, 0, len(order))
+	for _, key := range order {
+		w := byWorkload[key]
+		resolver.replicas(w)
+		w.warning = workloadWarning(*w)
+		workloads = append(workloads, *w)
+	}
+	sort.SliceStable(workloads, func(i, j int) bool {
+		return workloads[i].warning != "" && workloads[j].warning == ""
+	})
+	return workloads, nil
+}
+
+// workloadWarning explains what removing the pods does to the workload
+func workloadWarning(w workloadInfo) string {
+	if w.kind == "Pod" {
+		return "no controller, the pod will not be recreated"
+	}
+	if w.replicas < 0 || w.readyOnNode == 0 {
+		return ""
+	}
+	if w.kind == "StatefulSet" && w.replicas > 1 {
+		quorum := int(w.replicas)/2 + 1
+		if w.readyTotal-w.readyOnNode < quorum {
+			return fmt.Sprintf("leaves %d of %d members ready, below a quorum of %d",
+				w.readyTotal-w.readyOnNode, w.replicas, quorum)
+		}
+	}
+	if w.readyOnNode >= w.readyTotal {
+		return fmt.Sprintf("all %d ready replicas run on this node", w.readyTotal)
+	}
+	return ""
+}
+
+// ownerResolver walks owner references up to the top-level controller,
+// caching the objects it has fetched
+type ownerResolver struct {
+	ctx       context.Context
+	clientset *kubernetes.Clientset
+	owners    map[string]metav1.OwnerReference // kind/namespace/name -> controller
+}
+
+func newOwnerResolver(ctx context.Context, clientset *kubernetes.Clientset) *ownerResolver {
+	return &ownerResolver{ctx: ctx, clientset: clientset, owners: make(map[string]metav1.OwnerReference)}
+}
+
+// topLevel follows Pod→ReplicaSet→Deployment and Pod→Job→CronJob. Pods
+// without a controller are their own workload.
+func (r *ownerResolver) topLevel(pod *corev1.Pod) (kind, name string) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return "Pod", pod.Name
+	}
+	kind, name = ref.Kind, ref.Name
+	for kind == "ReplicaSet" || kind == "Job" {
+		parent, ok := r.controllerOf(kind, pod.Namespace, name)
+		if !ok {
+			break
+		}
+		kind, name = parent.Kind, parent.Name
+	}
+	return kind, name
+}
+
+// controllerOf returns the controller of a ReplicaSet or Job
+func (r *ownerResolver) controllerOf(kind, namespace, name string) (metav1.OwnerReference, bool) {
+	key := kind + "/" + namespace + "/" + name
+	if ref, ok := r.owners[key]; ok {
+		return ref, ref.Kind != ""
+	}
+
+	var meta *metav1.ObjectMeta
+	switch kind {
+	case "ReplicaSet":
+		if rs, err := r.clientset.AppsV1().ReplicaSets(namespace).Get(r.ctx, name, metav1.GetOptions{}); err == nil {
+			meta = &rs.ObjectMeta
+		}
+	case "Job":
+		if job, err := r.clientset.BatchV1().Jobs(namespace).Get(r.ctx, name, metav1.GetOptions{}); err == nil {
+			meta = &job.ObjectMeta
+		}
+	}
+
+	var ref metav1.OwnerReference
+	if meta != nil {
+		if controller := metav1.GetControllerOfNoCopy(meta); controller != nil {
+			ref = *controller
+		}
+	}
+	r.owners[key] = ref
+	return ref, ref.Kind != ""
+}
+
+// replicas fills in the desired and ready replicas of the workload. They
+// stay -1 for kinds without replicas or when the lookup fails.
+func (r *ownerResolver) replicas(w *workloadInfo) {
+	w.replicas, w.readyTotal = -1, -1
+	switch w.kind {
+	case "Deployment":
+		if d, err := r.clientset.AppsV1().Deployments(w.namespace).Get(r.ctx, w.name, metav1.GetOptions{}); err == nil {
+			w.replicas, w.readyTotal = replicasOf(d.Spec.Replicas), int(d.Status.ReadyReplicas)
+		}
+	case "StatefulSet":
+		if s, err := r.clientset.AppsV1().StatefulSets(w.namespace).Get(r.ctx, w.name, metav1.GetOptions{}); err == nil {
+			w.replicas, w.readyTotal = replicasOf(s.Spec.Replicas), int(s.Status.ReadyReplicas)
+		}
+	case "ReplicaSet":
+		if rs, err := r.clientset.AppsV1().ReplicaSets(w.namespace).Get(r.ctx, w.name, metav1.GetOptions{}); err == nil {
+			w.replicas, w.readyTotal = replicasOf(rs.Spec.Replicas), int(rs.Status.ReadyReplicas)
+		}
+	}
+}
+
+// replicasOf defaults unset replicas to 1 like the API server does
+func replicasOf(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// isPodReady reports whether the PodReady condition is true
+func isPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package plugin
+
+import "testing"
+
+func TestWorkloadWarning(t *testing.T) {
+	tests := []struct {
+		name     string
+		workload workloadInfo
+		want     string
+	}{
+		{"bare pod", workloadInfo{kind: "Pod", replicas: -1, readyTotal: -1}, "no controller, the pod will not be recreated"},
+		{"unknown replicas", workloadInfo{kind: "Deployment", replicas: -1, readyTotal: -1, readyOnNode: 1}, ""},
+		{"no ready pod on the node", workloadInfo{kind: "Deployment", replicas: 3, readyTotal: 2}, ""},
+		{"replicas elsewhere", workloadInfo{kind: "Deployment", replicas: 3, readyTotal: 3, readyOnNode: 1}, ""},
+		{"all replicas on the node", workloadInfo{kind: "Deployment", replicas: 2, readyTotal: 2, readyOnNode: 2}, "all 2 ready replicas run on this node"},
+		{"single replica", workloadInfo{kind: "StatefulSet", replicas: 1, readyTotal: 1, readyOnNode: 1}, "all 1 ready replicas run on this node"},
+		// 3 members need 2 for a quorum
+		{"quorum kept", workloadInfo{kind: "StatefulSet", replicas: 3, readyTotal: 3, readyOnNode: 1}, ""},
+		{"quorum lost", workloadInfo{kind: "StatefulSet", replicas: 3, readyTotal: 2, readyOnNode: 1}, "leaves 1 of 3 members ready, below a quorum of 2"},
+		{"two members on the node", workloadInfo{kind: "StatefulSet", replicas: 3, readyTotal: 3, readyOnNode: 2}, "leaves 1 of 3 members ready, below a quorum of 2"},
+		// 4 members need 3, 5 members need 3
+		{"even members", workloadInfo{kind: "StatefulSet", replicas: 4, readyTotal: 4, readyOnNode: 2}, "leaves 2 of 4 members ready, below a quorum of 3"},
+		{"five members", workloadInfo{kind: "StatefulSet", replicas: 5, readyTotal: 5, readyOnNode: 2}, ""},
+		{"all members on the node", workloadInfo{kind: "StatefulSet", replicas: 2, readyTotal: 2, readyOnNode: 2}, "leaves 0 of 2 members ready, below a quorum of 2"},
+		{"no quorum for deployments", workloadInfo{kind: "Deployment", replicas: 3, readyTotal: 2, readyOnNode: 1}, ""},
+	}
+	for _, tt := range tests {
+		if got := workloadWarning(tt.workload); got != tt.want {
+			t.Errorf("%s: workloadWarning = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}